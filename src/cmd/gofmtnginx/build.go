@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ChrisMcKee/gofmtnginx/internal/config"
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// runBuild renders configuration files from a crossplane style payload
+func runBuild(args []string) error {
+	cfg := config.New()
+	fs := newFlagSet("build", cfg)
+	dir := fs.String("d", ".", "Base directory relative file paths are written under")
+	format := fs.String("i", "", "Input format, json or yaml (default: from the file extension, json for stdin)")
+	force := fs.Bool("force", false, "Overwrite existing files")
+	stdout := fs.Bool("stdout", false, "Print the rendered files to stdout instead of writing them")
+	cfg.RegisterFormatFlags(fs)
+	parseFlags(fs, cfg, args, 1, 1)
+
+	payload, err := readPayload(fs.Arg(0), *format)
+	if err != nil {
+		return err
+	}
+
+	f := nginx.New(cfg.IndentSize, cfg.RemoveComments, cfg.PreserveNewlines)
+	for _, file := range payload.Config {
+		rendered, err := f.Render(file.Directives())
+		if err != nil {
+			return fmt.Errorf("error rendering %s: %w", file.File, err)
+		}
+
+		if *stdout {
+			fmt.Println("#" + nginx.FileHeader(file.File))
+			fmt.Println(strings.Join(rendered, "\n"))
+			continue
+		}
+
+		target := file.File
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(*dir, target)
+		}
+		if _, err := os.Stat(target); err == nil && !*force {
+			return fmt.Errorf("%s already exists, use -force to overwrite", target)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("error creating directory for %s: %w", target, err)
+		}
+		if err := nginx.WriteFormatted(target, rendered); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readPayload(name, format string) (*nginx.Payload, error) {
+	var input io.Reader = os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("error opening payload: %w", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	if format == "" {
+		format = "json"
+		if ext := filepath.Ext(name); ext == ".yaml" || ext == ".yml" {
+			format = "yaml"
+		}
+	}
+
+	payload := &nginx.Payload{}
+	var err error
+	switch format {
+	case "json":
+		err = json.NewDecoder(input).Decode(payload)
+	case "yaml":
+		err = yaml.NewDecoder(input).Decode(payload)
+	default:
+		return nil, fmt.Errorf("unsupported input format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding payload: %w", err)
+	}
+
+	return payload, nil
+}
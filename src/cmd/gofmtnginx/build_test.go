@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	const want = "http {\n  server {\n    listen 80;\n    return 200 \"a b\";\n  }\n}\n"
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{
+			name:    "json",
+			payload: `{"status": "ok", "errors": [], "config": [{"file": "nginx.conf", "status": "ok", "errors": [], "parsed": [{"directive": "http", "line": 1, "args": [], "block": [{"directive": "server", "line": 2, "args": [], "block": [{"directive": "listen", "line": 3, "args": ["80"]}, {"directive": "return", "line": 4, "args": ["200", "a b"]}]}]}]}]}`,
+		},
+		{
+			name: "yaml",
+			payload: `status: ok
+errors: []
+config:
+  - file: nginx.conf
+    status: ok
+    errors: []
+    parsed:
+      - directive: http
+        line: 1
+        args: []
+        block:
+          - directive: server
+            line: 2
+            args: []
+            block:
+              - directive: listen
+                line: 3
+                args: ["80"]
+              - directive: return
+                line: 4
+                args: ["200", "a b"]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(t.TempDir(), "payload."+tt.name)
+			if err := os.WriteFile(input, []byte(tt.payload), 0o644); err != nil {
+				t.Fatalf("Failed to create payload: %v", err)
+			}
+
+			if err := runBuild([]string{"-d", dir, input}); err != nil {
+				t.Fatalf("runBuild() error = %v", err)
+			}
+
+			got, err := os.ReadFile(filepath.Join(dir, "nginx.conf"))
+			if err != nil {
+				t.Fatalf("Failed to read built file: %v", err)
+			}
+			if string(got) != want {
+				t.Errorf("built nginx.conf =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
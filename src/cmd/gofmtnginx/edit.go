@@ -0,0 +1,250 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/internal/config"
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// editor applies edits to the files of an include graph. Every file is
+// loaded as a Document and the graph queries the documents' own
+// directives, so a match is exactly the node that gets edited.
+type editor struct {
+	graph *nginx.Graph
+	entry string
+	docs  map[string]*nginx.Document
+}
+
+// newEditor loads the graph reachable from entry. A positive indent
+// overrides the indentation detected in each file.
+func newEditor(entry, prefix string, indent int) (*editor, error) {
+	if prefix == "" {
+		prefix = filepath.Dir(entry)
+	}
+
+	graph := nginx.LoadGraph(prefix, entry)
+	if len(graph.Errors) > 0 {
+		return nil, graph.Errors[0]
+	}
+
+	docs := make(map[string]*nginx.Document, len(graph.Files))
+	for _, file := range graph.Files {
+		content, err := graph.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error opening input file: %w", err)
+		}
+		doc, err := nginx.NewDocument(file, content)
+		if err != nil {
+			return nil, err
+		}
+		if indent > 0 {
+			doc.Formatter = nginx.New(indent, false, false)
+		}
+		docs[file] = doc
+		graph.Configs[file] = doc.Directives
+	}
+
+	return &editor{
+		graph: graph,
+		entry: entry,
+		docs:  docs,
+	}, nil
+}
+
+// resolve returns the documents the matched directives belong to
+func (e *editor) resolve(matches []*nginx.Directive) ([]*nginx.Document, error) {
+	var docs []*nginx.Document
+	for _, m := range matches {
+		doc, ok := e.docs[m.File]
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: could not locate %q for editing", m.File, m.Line, m.Name)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// includers returns the file:line of every include directive that pulls
+// file into the graph
+func (e *editor) includers(file string) []string {
+	var from []string
+	for _, includer := range e.graph.Files {
+		for _, inc := range e.graph.Includes[includer] {
+			for _, match := range inc.Matches {
+				if match == file {
+					from = append(from, fmt.Sprintf("%s:%d", inc.File, inc.Line))
+				}
+			}
+		}
+	}
+	return from
+}
+
+// save writes the modified documents, noting on stderr any file that is
+// included from several places, as the edit applies to each of them
+func (e *editor) save(dryRun bool) error {
+	for _, file := range e.graph.Files {
+		doc := e.docs[file]
+		if !doc.Modified() {
+			continue
+		}
+		if from := e.includers(file); len(from) > 1 {
+			fmt.Fprintf(os.Stderr, "Note: %s is included from %d places (%s), the edit applies to each of them\n", file, len(from), strings.Join(from, ", "))
+		}
+		if dryRun {
+			content, err := doc.Bytes()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("# %s\n%s", doc.File, content)
+			continue
+		}
+		if err := doc.Save(); err != nil {
+			return fmt.Errorf("error writing %s: %w", doc.File, err)
+		}
+	}
+	return nil
+}
+
+// checkCount enforces that exactly one node matched unless all is set
+func checkCount(query string, matches []*nginx.Directive, all bool) error {
+	if len(matches) == 0 {
+		return fmt.Errorf("selector %q matched nothing", query)
+	}
+	if len(matches) > 1 && !all {
+		var locations []string
+		for _, m := range matches {
+			locations = append(locations, fmt.Sprintf("%s:%d", m.File, m.Line))
+		}
+		return fmt.Errorf("selector %q matched %d directives (%s), use -all to edit every match",
+			query, len(matches), strings.Join(locations, ", "))
+	}
+	return nil
+}
+
+type editFlags struct {
+	fs     *flag.FlagSet
+	cfg    *config.Config
+	config *string
+	all    *bool
+	dryRun *bool
+	indent int
+}
+
+func newEditFlags(name string) *editFlags {
+	cfg := config.New()
+	fs := newFlagSet(name, cfg)
+	ef := &editFlags{
+		fs:     fs,
+		cfg:    cfg,
+		config: fs.String("c", "/etc/nginx/nginx.conf", "Entry point of the configuration to edit"),
+		all:    fs.Bool("all", false, "Edit every matching directive instead of requiring exactly one"),
+		dryRun: fs.Bool("dry-run", false, "Print the edited files instead of writing them"),
+	}
+	fs.IntVar(&ef.indent, "indent", 0, "Number of spaces for indentation of new or rewritten directives (default: detected from each file)")
+	cfg.RegisterIncludeFlags(fs)
+	return ef
+}
+
+// runSet updates the arguments of the directive matched by a selector
+func runSet(args []string) error {
+	ef := newEditFlags("set")
+	create := ef.fs.Bool("create", false, "Add the directive to the parent block when nothing matches; the last step must follow '>'")
+	parseFlags(ef.fs, ef.cfg, args, 2, -1)
+
+	query, values := ef.fs.Arg(0), ef.fs.Args()[1:]
+	selector, err := nginx.ParseSelector(query)
+	if err != nil {
+		return err
+	}
+
+	e, err := newEditor(*ef.config, ef.cfg.Prefix, ef.indent)
+	if err != nil {
+		return err
+	}
+
+	matches := e.graph.Query(selector, e.entry)
+	if len(matches) == 0 && *create {
+		return e.create(query, selector, values, *ef.all, *ef.dryRun)
+	}
+	if err := checkCount(query, matches, *ef.all); err != nil {
+		return err
+	}
+
+	docs, err := e.resolve(matches)
+	if err != nil {
+		return err
+	}
+	for i, node := range matches {
+		if strings.Join(node.Args, "\x00") != strings.Join(values, "\x00") {
+			docs[i].SetArgs(node, values...)
+		}
+	}
+
+	return e.save(*ef.dryRun)
+}
+
+// create adds the directive named by the last step of selector to the
+// blocks matched by the rest of it
+func (e *editor) create(query string, selector *nginx.Selector, values []string, all, dryRun bool) error {
+	parent := selector.Parent()
+	if parent == nil || selector.Name() == "*" {
+		return fmt.Errorf("selector %q matched nothing and cannot be created, its last step must name a directive after '>'", query)
+	}
+
+	parents := e.graph.Query(parent, e.entry)
+	if err := checkCount(query+" (parent)", parents, all); err != nil {
+		return err
+	}
+
+	docs, err := e.resolve(parents)
+	if err != nil {
+		return err
+	}
+	for i, node := range parents {
+		if _, err := docs[i].Add(node, selector.Name(), values...); err != nil {
+			return err
+		}
+	}
+
+	return e.save(dryRun)
+}
+
+// runDelete removes the directive matched by a selector
+func runDelete(args []string) error {
+	ef := newEditFlags("delete")
+	parseFlags(ef.fs, ef.cfg, args, 1, 1)
+
+	query := ef.fs.Arg(0)
+	selector, err := nginx.ParseSelector(query)
+	if err != nil {
+		return err
+	}
+
+	e, err := newEditor(*ef.config, ef.cfg.Prefix, ef.indent)
+	if err != nil {
+		return err
+	}
+
+	matches := e.graph.Query(selector, e.entry)
+	if err := checkCount(query, matches, *ef.all); err != nil {
+		return err
+	}
+
+	docs, err := e.resolve(matches)
+	if err != nil {
+		return err
+	}
+	for i, node := range matches {
+		if err := docs[i].Remove(node); err != nil {
+			return err
+		}
+	}
+
+	return e.save(*ef.dryRun)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEdit(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		run   func(args []string) error
+		args  []string
+		want  map[string]string
+	}{
+		{
+			name:  "set second directive on a shared line",
+			files: map[string]string{"nginx.conf": "http {\n    server { listen 80; listen 443; }\n}\n"},
+			run:   runSet,
+			args:  []string{"listen[args=443]", "444"},
+			want:  map[string]string{"nginx.conf": "http {\n    server {\n        listen 80;\n        listen 444;\n    }\n}\n"},
+		},
+		{
+			name:  "set keeps other lines",
+			files: map[string]string{"nginx.conf": "http {\n    # compression\n    gzip on;\n    server {\n        listen 80;\n    }\n}\n"},
+			run:   runSet,
+			args:  []string{"gzip", "off"},
+			want:  map[string]string{"nginx.conf": "http {\n    # compression\n    gzip off;\n    server {\n        listen 80;\n    }\n}\n"},
+		},
+		{
+			name: "set in included file",
+			files: map[string]string{
+				"nginx.conf":       "http {\n    include conf.d/*.conf;\n}\n",
+				"conf.d/site.conf": "server {\n    listen 80;\n}\n",
+			},
+			run:  runSet,
+			args: []string{"http > server > listen", "8080"},
+			want: map[string]string{
+				"nginx.conf":       "http {\n    include conf.d/*.conf;\n}\n",
+				"conf.d/site.conf": "server {\n    listen 8080;\n}\n",
+			},
+		},
+		{
+			name:  "set all",
+			files: map[string]string{"nginx.conf": "http {\n    server { listen 80; listen 443; }\n}\n"},
+			run:   runSet,
+			args:  []string{"-all", "listen", "8080"},
+			want:  map[string]string{"nginx.conf": "http {\n    server {\n        listen 8080;\n        listen 8080;\n    }\n}\n"},
+		},
+		{
+			name:  "set create",
+			files: map[string]string{"nginx.conf": "http {\n    server {\n        listen 80;\n    }\n}\n"},
+			run:   runSet,
+			args:  []string{"-create", "server > server_name", "example.com"},
+			want:  map[string]string{"nginx.conf": "http {\n    server {\n        listen 80;\n        server_name example.com;\n    }\n}\n"},
+		},
+		{
+			name:  "set create with indent",
+			files: map[string]string{"nginx.conf": "http {\n    server {}\n}\n"},
+			run:   runSet,
+			args:  []string{"-create", "-indent", "2", "server > server_name", "example.com"},
+			want:  map[string]string{"nginx.conf": "http {\n    server {\n      server_name example.com;\n    }\n}\n"},
+		},
+		{
+			name:  "delete",
+			files: map[string]string{"nginx.conf": "http {\n    server {\n        listen 80;\n        listen 443;\n    }\n}\n"},
+			run:   runDelete,
+			args:  []string{"listen[args=80]"},
+			want:  map[string]string{"nginx.conf": "http {\n    server {\n        listen 443;\n    }\n}\n"},
+		},
+		{
+			name:  "delete second directive on a shared line",
+			files: map[string]string{"nginx.conf": "http {\n    server { listen 80; listen 443; }\n}\n"},
+			run:   runDelete,
+			args:  []string{"listen[args=443]"},
+			want:  map[string]string{"nginx.conf": "http {\n    server {\n        listen 80;\n    }\n}\n"},
+		},
+		{
+			name:  "delete all",
+			files: map[string]string{"nginx.conf": "http {\n    server {\n        listen 80;\n        listen 443;\n        root /srv;\n    }\n}\n"},
+			run:   runDelete,
+			args:  []string{"-all", "listen"},
+			want:  map[string]string{"nginx.conf": "http {\n    server {\n        root /srv;\n    }\n}\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := writeConfig(t, tt.files)
+			root := filepath.Dir(entry)
+
+			if err := tt.run(append([]string{"-c", entry}, tt.args...)); err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			for name, want := range tt.want {
+				got, err := os.ReadFile(filepath.Join(root, name))
+				if err != nil {
+					t.Fatalf("Failed to read %s: %v", name, err)
+				}
+				if string(got) != want {
+					t.Errorf("%s =\n%s\nwant\n%s", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEditExitCodes(t *testing.T) {
+	const config = "http {\n    server {\n        listen 80;\n        listen 443;\n    }\n}\n"
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "set one match", args: []string{"set", "listen[args=80]", "8080"}, code: 0},
+		{name: "set no match", args: []string{"set", "server_name", "example.com"}, code: 1},
+		{name: "set several matches", args: []string{"set", "listen", "8080"}, code: 1},
+		{name: "create without parent", args: []string{"set", "-create", "server > location > root", "/srv"}, code: 1},
+		{name: "delete no match", args: []string{"delete", "server_name"}, code: 1},
+		{name: "delete several matches", args: []string{"delete", "listen"}, code: 1},
+		{name: "missing selector", args: []string{"delete"}, code: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := writeConfig(t, map[string]string{"nginx.conf": config})
+
+			if code := runMain(t, append([]string{tt.args[0], "-c", entry}, tt.args[1:]...)...); code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+
+			if tt.code != 0 {
+				got, err := os.ReadFile(entry)
+				if err != nil {
+					t.Fatalf("Failed to read %s: %v", entry, err)
+				}
+				if string(got) != config {
+					t.Errorf("failed run changed %s:\n%s", entry, got)
+				}
+			}
+		})
+	}
+}
+
+func TestEditorIncluders(t *testing.T) {
+	entry := writeConfig(t, map[string]string{
+		"nginx.conf":          "http {\n    server {\n        include snippets/ssl.conf;\n    }\n    server {\n        include snippets/ssl.conf;\n    }\n    include conf.d/*.conf;\n}\n",
+		"snippets/ssl.conf":   "ssl_protocols TLSv1.3;\n",
+		"conf.d/default.conf": "server {\n    listen 80;\n}\n",
+	})
+	root := filepath.Dir(entry)
+
+	e, err := newEditor(entry, "", 0)
+	if err != nil {
+		t.Fatalf("newEditor() error = %v", err)
+	}
+
+	tests := map[string]int{
+		"nginx.conf":          0,
+		"snippets/ssl.conf":   2,
+		"conf.d/default.conf": 1,
+	}
+	for name, want := range tests {
+		if got := e.includers(filepath.Join(root, name)); len(got) != want {
+			t.Errorf("includers(%s) = %v, want %d", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/internal/config"
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// runExplode recreates the file tree captured in nginx -T output
+func runExplode(args []string) error {
+	cfg := config.New()
+	fs := newFlagSet("explode", cfg)
+	output := fs.String("o", "", "Directory to recreate the configuration tree in (required)")
+	cfg.RegisterFormatFlags(fs)
+	parseFlags(fs, cfg, args, 1, 1)
+
+	if *output == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var input io.Reader = os.Stdin
+	if fs.Arg(0) != "-" {
+		file, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("error opening dump: %w", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	files, err := nginx.ParseDump(input)
+	if err != nil {
+		return fmt.Errorf("error reading dump: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no \"# configuration file\" headers found in %s", fs.Arg(0))
+	}
+
+	f := nginx.New(cfg.IndentSize, cfg.RemoveComments, cfg.PreserveNewlines)
+	root := nginx.DumpRoot(files)
+
+	for _, file := range files {
+		rel, err := filepath.Rel(root, filepath.Clean(file.Path))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("refusing to write %s outside %s", file.Path, *output)
+		}
+		target := filepath.Join(*output, rel)
+
+		formatted, err := f.Format(strings.NewReader(strings.Join(file.Lines, "\n")))
+		if err != nil {
+			return fmt.Errorf("error formatting %s: %w", file.Path, err)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("error creating directory for %s: %w", target, err)
+		}
+		if err := nginx.WriteFormatted(target, formatted); err != nil {
+			return err
+		}
+		if cfg.Verbose {
+			log.Printf("Wrote %s\n", target)
+		}
+	}
+
+	fmt.Printf("Recreated %d file(s) from %s under %s\n", len(files), root, *output)
+	return nil
+}
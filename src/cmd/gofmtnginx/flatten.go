@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/internal/config"
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// runFlatten inlines every include reachable from an entry point and writes
+// the result as a single formatted file
+func runFlatten(args []string) error {
+	cfg := config.New()
+	fs := newFlagSet("flatten", cfg)
+	output := fs.String("o", "", "Write the flattened configuration to this file instead of stdout")
+	cfg.RegisterFormatFlags(fs)
+	cfg.RegisterIncludeFlags(fs)
+	parseFlags(fs, cfg, args, 1, 1)
+
+	entry := fs.Arg(0)
+	prefix := cfg.Prefix
+	if prefix == "" {
+		prefix = filepath.Dir(entry)
+	}
+
+	graph := nginx.LoadGraph(prefix, entry)
+	if len(graph.Errors) > 0 {
+		return graph.Errors[0]
+	}
+	for _, d := range graph.Diagnostics("") {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", d)
+	}
+
+	f := nginx.New(cfg.IndentSize, cfg.RemoveComments, cfg.PreserveNewlines)
+	formatted, err := f.Render(graph.Flatten(entry))
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err := fmt.Println(strings.Join(formatted, "\n"))
+		return err
+	}
+	return nginx.WriteFormatted(*output, formatted)
+}
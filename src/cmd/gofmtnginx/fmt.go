@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ChrisMcKee/gofmtnginx/internal/config"
+	"github.com/ChrisMcKee/gofmtnginx/internal/formatter"
+)
+
+// runFmt formats every nginx file in a directory
+func runFmt(args []string) error {
+	cfg := config.New()
+	fs := newFlagSet("fmt", cfg)
+	cfg.RegisterFormatFlags(fs)
+	cfg.RegisterIncludeFlags(fs)
+	cfg.RegisterProcessFlags(fs)
+	parseFlags(fs, cfg, args, 1, 1)
+
+	f := formatter.New(cfg)
+	if err := f.ProcessDirectory(fs.Arg(0)); err != nil {
+		return err
+	}
+
+	fmt.Println(f.Stats())
+	return nil
+}
+
+// runCheck reports files whose formatting would change without writing them
+func runCheck(args []string) error {
+	cfg := config.New()
+	fs := newFlagSet("check", cfg)
+	cfg.RegisterFormatFlags(fs)
+	cfg.RegisterIncludeFlags(fs)
+	cfg.RegisterProcessFlags(fs)
+	parseFlags(fs, cfg, args, 1, 1)
+
+	cfg.Check = true
+	cfg.DryRun = true
+	cfg.Backup = false
+
+	f := formatter.New(cfg)
+	if err := f.ProcessDirectory(fs.Arg(0)); err != nil {
+		return err
+	}
+
+	stats := f.Stats()
+	fmt.Println(stats)
+	if stats.FilesChanged > 0 || stats.FilesFailed > 0 {
+		return fmt.Errorf("%d file(s) need formatting, %d failed", stats.FilesChanged, stats.FilesFailed)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ChrisMcKee/gofmtnginx/internal/config"
+	"github.com/ChrisMcKee/gofmtnginx/internal/lint"
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// runLint checks the configuration reachable from an entry point against
+// the lint rules and exits non-zero if anything at or above the -fail-on
+// severity is found
+func runLint(args []string) error {
+	cfg := config.New()
+	fs := newFlagSet("lint", cfg)
+	list := fs.Bool("rules", false, "List the available rules and exit")
+	fix := fs.Bool("fix", false, "Apply the safe rewrites available for the findings")
+	dryRun := fs.Bool("dry-run", false, "With -fix, print a diff of the rewrites instead of writing them")
+	indent := fs.Int("indent", 0, "With -fix, number of spaces for indentation of rewritten directives (default: detected from each file)")
+	headers := fs.Bool("headers", false, "Print the add_header directives in effect in each location and exit")
+	vhosts := fs.Bool("vhosts", false, "Print which server owns each address, port and server name and exit")
+	failOn := fs.String("fail-on", "warning", "Lowest severity that makes the command exit non-zero: info, warning or error")
+	cfg.RegisterIncludeFlags(fs)
+	cfg.RegisterLintFlags(fs)
+	parseFlags(fs, cfg, args, 0, 1)
+
+	if *list {
+		for _, r := range lint.Rules() {
+			fmt.Printf("%-20s %-8s %s\n", r.ID(), r.Severity(), r.Description())
+		}
+		for _, name := range lint.Sets() {
+			fmt.Printf("%-20s %-8s %s\n", name, "set", strings.Join(lint.Set(name), ", "))
+		}
+		return nil
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	threshold, err := lint.ParseSeverity(*failOn)
+	if err != nil {
+		return err
+	}
+	l, err := lint.New(cfg.EnableRules, cfg.DisableRules)
+	if err != nil {
+		return err
+	}
+	if *indent > 0 {
+		l.Formatter = nginx.New(*indent, false, false)
+	}
+
+	tree := lint.Load(cfg.Prefix, fs.Arg(0))
+	tree.AutoindexAllow = cfg.AutoindexAllow
+	if cfg.Schema != "" {
+		schema, err := lint.LoadSchema(cfg.Schema)
+		if err != nil {
+			return err
+		}
+		tree.Schema = tree.Schema.Extend(schema)
+	}
+	if cfg.TargetVersion != "" {
+		if tree.Target, err = lint.ParseVersion(cfg.TargetVersion); err != nil {
+			return err
+		}
+	}
+
+	if *headers {
+		printHeaders(tree)
+		return nil
+	}
+	if *vhosts {
+		printVhosts(tree)
+		return nil
+	}
+
+	if *fix {
+		e, err := l.Fix(tree)
+		if err != nil {
+			return err
+		}
+		changed := e.Changed()
+		for _, file := range changed {
+			if *dryRun {
+				text, _ := e.Text(file)
+				fmt.Print(nginx.Diff(file, file, splitLines(e.Original(file)), splitLines(text)))
+			}
+		}
+		if !*dryRun {
+			if err := e.Save(); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Fixed %d file(s)\n", len(changed))
+		}
+		// report what is left
+		tree = tree.Reload(e.Overlay())
+	}
+
+	failed := false
+	for _, d := range l.Lint(tree) {
+		fmt.Println(d)
+		failed = failed || d.Severity >= threshold
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// printHeaders lists each location with the headers it adds and where
+// they are set
+func printHeaders(tree *lint.Tree) {
+	for _, lh := range lint.EffectiveHeaders(tree) {
+		loc := lh.Location
+		fmt.Printf("%s:%d: location %s\n", loc.File, loc.Line, strings.Join(loc.Args, " "))
+		for _, h := range lh.Headers {
+			fmt.Printf("    %s (%s:%d)\n", strings.Join(h.Args, " "), h.File, h.Line)
+		}
+	}
+}
+
+// printVhosts prints a table of the socket and name pairs of the http
+// servers with the server that owns each, the first to claim it, and any
+// later servers claiming it too
+func printVhosts(tree *lint.Tree) {
+	type row struct {
+		vhost  lint.Vhost
+		others []string
+	}
+	var rows []*row
+	owners := make(map[string]*row)
+	for _, v := range lint.Vhosts(tree) {
+		key := v.Socket + " " + v.Name
+		if r, ok := owners[key]; ok {
+			if r.vhost.Server != v.Server {
+				r.others = append(r.others, fmt.Sprintf("%s:%d", v.Server.File, v.Server.Line))
+			}
+			continue
+		}
+		owners[key] = &row{vhost: v}
+		rows = append(rows, owners[key])
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].vhost.Socket < rows[j].vhost.Socket })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LISTEN\tSERVER NAME\tDEFAULT\tOWNER\tALSO CLAIMED BY")
+	for _, r := range rows {
+		v := r.vhost
+		name, def := v.Name, ""
+		if name == "" {
+			name = `""`
+		}
+		if v.Default {
+			def = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s:%d\t%s\n", v.Socket, name, def, v.Server.File, v.Server.Line, strings.Join(r.others, ", "))
+	}
+	w.Flush()
+}
+
+// splitLines splits file content into lines without the final newline
+func splitLines(content []byte) []string {
+	text := strings.TrimSuffix(string(content), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
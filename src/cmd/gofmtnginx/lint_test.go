@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestLintExitCodes(t *testing.T) {
+	// add-header-always is info, server-tokens a warning and
+	// ssl-protocols an error
+	tests := []struct {
+		name   string
+		config string
+		args   []string
+		code   int
+	}{
+		{name: "clean", config: "http {\n  server_tokens off;\n}\n", code: 0},
+		{name: "info only", config: "http {\n  add_header X-A b;\n}\n", code: 0},
+		{name: "info with fail-on info", config: "http {\n  add_header X-A b;\n}\n", args: []string{"-fail-on", "info"}, code: 1},
+		{name: "warning", config: "http {\n  server_tokens on;\n}\n", code: 1},
+		{name: "warning with fail-on error", config: "http {\n  server_tokens on;\n}\n", args: []string{"-fail-on", "error"}, code: 0},
+		{name: "error with fail-on error", config: "http {\n  ssl_protocols SSLv3;\n}\n", args: []string{"-fail-on", "error"}, code: 1},
+		{name: "invalid fail-on", config: "http {}\n", args: []string{"-fail-on", "fatal"}, code: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := writeConfig(t, map[string]string{"nginx.conf": tt.config})
+			args := append([]string{"lint", "-enable", "security"}, tt.args...)
+			if code := runMain(t, append(args, entry)...); code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+		})
+	}
+}
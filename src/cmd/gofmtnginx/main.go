@@ -5,22 +5,185 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
 
 	"github.com/ChrisMcKee/gofmtnginx/internal/config"
-	"github.com/ChrisMcKee/gofmtnginx/internal/formatter"
 )
 
+var version = "dev"
+
+// command is a gofmtnginx subcommand
+type command struct {
+	usage   string
+	summary string
+	failure string
+	run     func(args []string) error
+}
+
+// commands is filled in by init since the commands refer back to it for
+// their usage text
+var commands map[string]*command
+
+func init() {
+	commands = map[string]*command{
+		"fmt": {
+			usage:   "gofmtnginx fmt [flags] <directory>",
+			summary: "Format nginx configuration files in place (default command)",
+			failure: "Error processing directory",
+			run:     runFmt,
+		},
+		"check": {
+			usage:   "gofmtnginx check [flags] <directory>",
+			summary: "Report files that are not formatted and exit non-zero if there are any",
+			failure: "Error checking directory",
+			run:     runCheck,
+		},
+		"flatten": {
+			usage:   "gofmtnginx flatten [flags] <nginx.conf>",
+			summary: "Inline every include into a single nginx -T style bundle",
+			failure: "Error flattening configuration",
+			run:     runFlatten,
+		},
+		"explode": {
+			usage:   "gofmtnginx explode -o <directory> [flags] <dump.txt|->",
+			summary: "Recreate a configuration tree from nginx -T output",
+			failure: "Error exploding dump",
+			run:     runExplode,
+		},
+		"parse": {
+			usage:   "gofmtnginx parse [flags] <nginx.conf>",
+			summary: "Print the parsed configuration as crossplane-compatible JSON",
+			failure: "Error parsing configuration",
+			run:     runParse,
+		},
+		"build": {
+			usage:   "gofmtnginx build [flags] <payload.json|payload.yaml|->",
+			summary: "Render configuration files from a crossplane-style JSON or YAML payload",
+			failure: "Error building configuration",
+			run:     runBuild,
+		},
+		"lint": {
+			usage:   "gofmtnginx lint [flags] <nginx.conf>",
+			summary: "Report semantic problems in the configuration reachable from an entry point",
+			failure: "Error linting configuration",
+			run:     runLint,
+		},
+		"query": {
+			usage:   "gofmtnginx query [flags] <selector> <nginx.conf>",
+			summary: "Print directives matching a selector with their file and line",
+			failure: "Error querying configuration",
+			run:     runQuery,
+		},
+		"set": {
+			usage:   "gofmtnginx set [flags] <selector> <value>...",
+			summary: "Set the arguments of the directive matching a selector",
+			failure: "Error setting directive",
+			run:     runSet,
+		},
+		"delete": {
+			usage:   "gofmtnginx delete [flags] <selector>",
+			summary: "Delete the directive matching a selector",
+			failure: "Error deleting directive",
+			run:     runDelete,
+		},
+		"version": {
+			usage:   "gofmtnginx version",
+			summary: "Print the version",
+			run: func([]string) error {
+				fmt.Println(version)
+				return nil
+			},
+		},
+	}
+}
+
 func main() {
-	cfg := config.ParseFlags()
+	args := os.Args[1:]
+	if len(args) == 0 {
+		usage()
+		os.Exit(1)
+	}
+
+	name := args[0]
+	switch {
+	case name == "help" || name == "-h" || name == "-help" || name == "--help":
+		help(args[1:])
+		return
+	case commands[name] != nil:
+		args = args[1:]
+	default:
+		// gofmtnginx [flags] <directory> predates subcommands
+		name = "fmt"
+	}
+
+	cmd := commands[name]
+	if err := cmd.run(args); err != nil {
+		log.Printf("%s: %v\n", cmd.failure, err)
+		os.Exit(1)
+	}
+}
+
+// newFlagSet returns a flag set for a command with the global flags and
+// per-command help already registered
+func newFlagSet(name string, cfg *config.Config) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		cmd := commands[name]
+		fmt.Fprintf(fs.Output(), "%s\n\nUsage: %s\n\nFlags:\n", cmd.summary, cmd.usage)
+		fs.PrintDefaults()
+	}
+	cfg.RegisterGlobalFlags(fs)
+	return fs
+}
+
+// parseFlags parses args, finishes the configuration and checks the
+// number of positional arguments, printing usage when it is wrong
+func parseFlags(fs *flag.FlagSet, cfg *config.Config, args []string, minArgs, maxArgs int) {
+	fs.Parse(args)
+
+	if err := cfg.Finish(); err != nil {
+		fmt.Fprintf(fs.Output(), "%v\n\n", err)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if fs.NArg() < minArgs || (maxArgs >= 0 && fs.NArg() > maxArgs) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
 	setupLogging(cfg.Verbose)
+}
+
+func usage() {
+	fmt.Println("Usage: gofmtnginx <command> [flags] [arguments]")
+	fmt.Println("       gofmtnginx [flags] <directory>    (same as gofmtnginx fmt)")
+	fmt.Println("\nCommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %-8s %s\n", name, commands[name].summary)
+	}
+
+	fmt.Println("\nRun \"gofmtnginx help <command>\" for the flags of a command.")
+}
+
+func help(args []string) {
+	if len(args) == 0 {
+		usage()
+		return
+	}
 
-	f := formatter.New(cfg)
-	if err := f.ProcessDirectory(flag.Arg(0)); err != nil {
-		log.Printf("Error processing directory: %v\n", err)
+	if commands[args[0]] == nil {
+		fmt.Printf("Unknown command %q\n\n", args[0])
+		usage()
 		os.Exit(1)
 	}
 
-	fmt.Println(f.Stats())
+	commands[args[0]].run([]string{"-h"})
 }
 
 func setupLogging(verbose bool) {
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs gofmtnginx itself when runMain starts the test binary
+// again, so exit codes can be checked
+func TestMain(m *testing.M) {
+	if args := os.Getenv("GOFMTNGINX_TEST_ARGS"); args != "" {
+		os.Args = append([]string{"gofmtnginx"}, strings.Split(args, "\n")...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs gofmtnginx with args in a child process and returns its
+// exit code
+func runMain(t *testing.T, args ...string) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "GOFMTNGINX_TEST_ARGS="+strings.Join(args, "\n"))
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatalf("Failed to run gofmtnginx: %v", err)
+	}
+	return 0
+}
+
+// writeConfig writes files into a temporary directory and returns the
+// path of its nginx.conf
+func writeConfig(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	return filepath.Join(root, "nginx.conf")
+}
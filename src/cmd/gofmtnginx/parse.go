@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/internal/config"
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// runParse prints the parsed configuration graph in crossplane's JSON schema
+func runParse(args []string) error {
+	cfg := config.New()
+	fs := newFlagSet("parse", cfg)
+	format := fs.String("o", "json", "Output format (json)")
+	output := fs.String("out", "", "Write the output to this file instead of stdout")
+	includeComments := fs.Bool("include-comments", false, "Include comments as \"#\" directives")
+	indent := fs.Int("json-indent", 0, "Number of spaces to indent JSON output (default: compact)")
+	cfg.RegisterIncludeFlags(fs)
+	parseFlags(fs, cfg, args, 1, 1)
+
+	if *format != "json" {
+		return fmt.Errorf("unsupported output format %q", *format)
+	}
+
+	entry := fs.Arg(0)
+	prefix := cfg.Prefix
+	if prefix == "" {
+		prefix = filepath.Dir(entry)
+	}
+
+	payload := nginx.LoadGraph(prefix, entry).Payload(*includeComments)
+
+	var data []byte
+	var err error
+	if *indent > 0 {
+		data, err = json.MarshalIndent(payload, "", strings.Repeat(" ", *indent))
+	} else {
+		data, err = json.Marshal(payload)
+	}
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*output, data, 0o644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if payload.Status != "ok" {
+		os.Exit(1)
+	}
+	return nil
+}
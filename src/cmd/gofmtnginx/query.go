@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ChrisMcKee/gofmtnginx/internal/config"
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// runQuery prints the directives matching a selector across the include
+// graph of an entry point
+func runQuery(args []string) error {
+	cfg := config.New()
+	fs := newFlagSet("query", cfg)
+	cfg.RegisterIncludeFlags(fs)
+	parseFlags(fs, cfg, args, 2, 2)
+
+	selector, err := nginx.ParseSelector(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	entry := fs.Arg(1)
+	prefix := cfg.Prefix
+	if prefix == "" {
+		prefix = filepath.Dir(entry)
+	}
+
+	graph := nginx.LoadGraph(prefix, entry)
+	for _, err := range graph.Errors {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	matches := graph.Query(selector, entry)
+	for _, d := range matches {
+		fmt.Printf("%s:%d: %s\n", d.File, d.Line, d)
+	}
+
+	if len(matches) == 0 {
+		os.Exit(1)
+	}
+	return nil
+}
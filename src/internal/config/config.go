@@ -3,7 +3,6 @@ package config
 import (
 	"flag"
 	"fmt"
-	"os"
 	"strings"
 )
 
@@ -17,34 +16,117 @@ type Config struct {
 	MaxWorkers       int
 	Extensions       []string
 	PreserveNewlines bool
+	Verify           bool
+	SemanticCheck    bool
+	NginxTest        string
+	NginxConf        string
+	Transactional    bool
+	Entries          []string
+	Prefix           string
+	Check            bool
+	EnableRules      []string
+	DisableRules     []string
+	Schema           string
+	TargetVersion    string
+	AutoindexAllow   []string
+
+	extensions     string
+	entries        string
+	enableRules    string
+	disableRules   string
+	autoindexAllow string
+}
+
+// New returns a Config holding the default for every setting
+func New() *Config {
+	return &Config{
+		IndentSize:    2,
+		Concurrent:    true,
+		MaxWorkers:    4,
+		Extensions:    []string{".conf", ".proxy"},
+		Verify:        true,
+		SemanticCheck: true,
+		NginxConf:     "nginx.conf",
+		extensions:    ".conf,.proxy",
+	}
+}
+
+// RegisterGlobalFlags registers the flags shared by every command
+func (c *Config) RegisterGlobalFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.Verbose, "verbose", c.Verbose, "Enable verbose logging")
+}
+
+// RegisterFormatFlags registers the flags controlling how configuration is laid out
+func (c *Config) RegisterFormatFlags(fs *flag.FlagSet) {
+	fs.IntVar(&c.IndentSize, "indent", c.IndentSize, "Number of spaces for indentation")
+	fs.BoolVar(&c.RemoveComments, "removecomments", c.RemoveComments, "Remove comments from the configuration file")
+	fs.BoolVar(&c.PreserveNewlines, "preserve-newlines", c.PreserveNewlines, "Preserve existing newlines between blocks")
+}
+
+// RegisterIncludeFlags registers the flags controlling how include paths are resolved
+func (c *Config) RegisterIncludeFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Prefix, "prefix", c.Prefix, "Directory relative include paths are resolved against, like nginx -p (default: directory of the first entry point)")
+}
+
+// RegisterProcessFlags registers the flags controlling how a directory tree is processed
+func (c *Config) RegisterProcessFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, "Show what would be done without making changes")
+	fs.BoolVar(&c.Backup, "backup", c.Backup, "Create backup files before modifying")
+	fs.BoolVar(&c.Concurrent, "concurrent", c.Concurrent, "Process files concurrently")
+	fs.IntVar(&c.MaxWorkers, "workers", c.MaxWorkers, "Number of concurrent workers")
+	fs.BoolVar(&c.Verify, "verify", c.Verify, "Format the output a second time and refuse to write if it changes")
+	fs.BoolVar(&c.SemanticCheck, "semantic-check", c.SemanticCheck, "Refuse to write if the formatted output tokenizes differently from the original")
+	fs.StringVar(&c.NginxTest, "nginx-test", c.NginxTest, "Path to an nginx binary used to validate the formatted tree with nginx -t before writing")
+	fs.StringVar(&c.NginxConf, "nginx-conf", c.NginxConf, "Main configuration file passed to nginx -t, relative to the directory")
+	fs.BoolVar(&c.Transactional, "transactional", c.Transactional, "Format the whole tree before writing and leave every file unchanged if anything fails")
+	fs.StringVar(&c.extensions, "extensions", c.extensions, "Comma-separated list of file extensions to process")
+	fs.StringVar(&c.entries, "entry", c.entries, "Comma-separated list of entry point files; only files reachable through include directives are formatted")
+}
+
+// RegisterLintFlags registers the flags selecting which lint rules run
+func (c *Config) RegisterLintFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.enableRules, "enable", c.enableRules, "Comma-separated list of lint rules to run (default: all)")
+	fs.StringVar(&c.disableRules, "disable", c.disableRules, "Comma-separated list of lint rules to skip")
+	fs.StringVar(&c.TargetVersion, "target-version", c.TargetVersion, "nginx version the configuration must work with, such as 1.25 (default: the newest)")
+	fs.StringVar(&c.Schema, "schema", c.Schema, "JSON file describing additional directives, such as those of third-party modules")
+	fs.StringVar(&c.autoindexAllow, "autoindex-allow", c.autoindexAllow, "Comma-separated list of location prefixes where autoindex is intended")
 }
 
-func ParseFlags() *Config {
-	config := &Config{}
-	flag.IntVar(&config.IndentSize, "indent", 2, "Number of spaces for indentation")
-	flag.BoolVar(&config.DryRun, "dry-run", false, "Show what would be done without making changes")
-	flag.BoolVar(&config.Verbose, "verbose", false, "Enable verbose logging")
-	flag.BoolVar(&config.RemoveComments, "removecomments", false, "Remove comments from the configuration file")
-	flag.BoolVar(&config.Backup, "backup", false, "Create backup files before modifying")
-	flag.BoolVar(&config.Concurrent, "concurrent", true, "Process files concurrently")
-	flag.IntVar(&config.MaxWorkers, "workers", 4, "Number of concurrent workers")
-	flag.BoolVar(&config.PreserveNewlines, "preserve-newlines", false, "Preserve existing newlines between blocks")
-
-	extensions := flag.String("extensions", ".conf,.proxy", "Comma-separated list of file extensions to process")
-	flag.Parse()
-
-	if len(flag.Args()) < 1 {
-		fmt.Println("Usage: gofmtnginx [flags] <directory>")
-		flag.PrintDefaults()
-		os.Exit(1)
+// Finish applies the flags that need processing after parsing
+func (c *Config) Finish() error {
+	if c.IndentSize < 0 {
+		return fmt.Errorf("indent must not be negative, got %d", c.IndentSize)
+	}
+	if c.MaxWorkers < 1 {
+		return fmt.Errorf("workers must be at least 1, got %d", c.MaxWorkers)
 	}
 
-	config.Extensions = strings.Split(*extensions, ",")
-	for i, ext := range config.Extensions {
+	c.Extensions = strings.Split(c.extensions, ",")
+	for i, ext := range c.Extensions {
 		if !strings.HasPrefix(ext, ".") {
-			config.Extensions[i] = "." + ext
+			c.Extensions[i] = "." + ext
 		}
 	}
 
-	return config
+	c.Entries = nil
+	if c.entries != "" {
+		c.Entries = strings.Split(c.entries, ",")
+	}
+
+	c.EnableRules = splitList(c.enableRules)
+	c.DisableRules = splitList(c.disableRules)
+	c.AutoindexAllow = splitList(c.autoindexAllow)
+
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
@@ -0,0 +1,113 @@
+package formatter
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// walkIncludes calls process for every file under directory reachable from
+// the configured entry points instead of guessing nginx files by extension.
+// Include problems and files that no entry point reaches are reported.
+func (f *Formatter) walkIncludes(directory string, process func(string) error) error {
+	graph := f.loadGraph(directory)
+	for _, err := range graph.Errors {
+		f.stats.IncrementFailed()
+		log.Printf("Error loading include graph: %v\n", err)
+	}
+	for _, d := range graph.Diagnostics(directory) {
+		log.Printf("Warning: %s\n", d)
+	}
+
+	root, err := filepath.Abs(directory)
+	if err != nil {
+		return err
+	}
+
+	reachable := make(map[string]bool)
+	var inside []string
+	for _, file := range graph.Files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			continue
+		}
+		reachable[abs] = true
+		// files outside the directory are reported above and left alone
+		if rel, err := filepath.Rel(root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			inside = append(inside, file)
+		}
+	}
+
+	err = filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			log.Printf("Error accessing path %q: %v\n", path, err)
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		abs, _ := filepath.Abs(path)
+		if !reachable[abs] && !strings.HasSuffix(path, ".bak") {
+			f.stats.IncrementSkipped()
+			log.Printf("Not reachable from any entry point: %s\n", path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	files := make(chan string, len(inside))
+	for _, file := range inside {
+		files <- file
+	}
+	close(files)
+
+	workers := 1
+	if f.config.Concurrent {
+		workers = f.config.MaxWorkers
+	}
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range files {
+				if err := process(file); err != nil {
+					log.Printf("Error processing file %s: %v\n", file, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// loadGraph resolves the configured entry points relative to directory.
+// Without an explicit prefix, includes resolve against the directory of
+// the first entry point, matching nginx's default for -c.
+func (f *Formatter) loadGraph(directory string) *nginx.Graph {
+	var entries []string
+	for _, entry := range f.config.Entries {
+		if !filepath.IsAbs(entry) {
+			entry = filepath.Join(directory, entry)
+		}
+		entries = append(entries, entry)
+	}
+
+	prefix := f.config.Prefix
+	if prefix == "" && len(entries) > 0 {
+		prefix = filepath.Dir(entries[0])
+	}
+
+	return nginx.LoadGraph(prefix, entries...)
+}
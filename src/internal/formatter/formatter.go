@@ -1,6 +1,7 @@
 package formatter
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -27,13 +28,24 @@ func New(cfg *config.Config) *Formatter {
 }
 
 func (f *Formatter) ProcessDirectory(directory string) error {
+	if f.config.Transactional || f.config.NginxTest != "" {
+		return f.processDirectoryStaged(directory)
+	}
+	return f.walk(directory, f.processFile)
+}
+
+// walk calls process for every nginx file under directory
+func (f *Formatter) walk(directory string, process func(string) error) error {
+	if len(f.config.Entries) > 0 {
+		return f.walkIncludes(directory, process)
+	}
 	if f.config.Concurrent {
-		return f.processDirectoryConcurrent(directory)
+		return f.processDirectoryConcurrent(directory, process)
 	}
-	return f.processDirectorySequential(directory)
+	return f.processDirectorySequential(directory, process)
 }
 
-func (f *Formatter) processDirectorySequential(directory string) error {
+func (f *Formatter) processDirectorySequential(directory string, process func(string) error) error {
 	return filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			log.Printf("Error accessing path %q: %v\n", path, err)
@@ -42,7 +54,7 @@ func (f *Formatter) processDirectorySequential(directory string) error {
 
 		if !info.IsDir() && info.Name() != ".git" {
 			if f.shouldProcessFile(path) {
-				if err := f.processFile(path); err != nil {
+				if err := process(path); err != nil {
 					log.Printf("Error processing file %s: %v\n", path, err)
 				}
 			} else {
@@ -56,7 +68,7 @@ func (f *Formatter) processDirectorySequential(directory string) error {
 	})
 }
 
-func (f *Formatter) processDirectoryConcurrent(directory string) error {
+func (f *Formatter) processDirectoryConcurrent(directory string, process func(string) error) error {
 	files := make(chan string, 100)
 	var wg sync.WaitGroup
 
@@ -66,7 +78,7 @@ func (f *Formatter) processDirectoryConcurrent(directory string) error {
 			defer wg.Done()
 			for file := range files {
 				if f.shouldProcessFile(file) {
-					if err := f.processFile(file); err != nil {
+					if err := process(file); err != nil {
 						log.Printf("Error processing file %s: %v\n", file, err)
 					}
 				} else {
@@ -108,6 +120,24 @@ func (f *Formatter) shouldProcessFile(path string) bool {
 }
 
 func (f *Formatter) processFile(fileName string) error {
+	formatted, err := f.formatFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	if !f.config.DryRun {
+		if err := f.writeFile(fileName, formatted); err != nil {
+			f.stats.IncrementFailed()
+			return err
+		}
+	}
+
+	f.stats.IncrementProcessed()
+	return nil
+}
+
+// formatFile formats a file in memory and runs the configured safety checks
+func (f *Formatter) formatFile(fileName string) ([]string, error) {
 	if f.config.Verbose {
 		log.Printf("Processing file: %s\n", fileName)
 	}
@@ -115,27 +145,52 @@ func (f *Formatter) processFile(fileName string) error {
 	formatted, err := f.nginx.FormatFile(fileName)
 	if err != nil {
 		f.stats.IncrementFailed()
-		return err
+		return nil, err
 	}
 
-	if !f.config.DryRun {
-		if f.config.Backup {
-			backupFile := fileName + ".bak"
-			backupContent := []byte(strings.Join(formatted, "\n") + "\n")
-			if err := os.WriteFile(backupFile, backupContent, 0o644); err != nil {
-				f.stats.IncrementFailed()
-				return err
-			}
+	if f.config.Verify {
+		if err := f.nginx.Verify(formatted); err != nil {
+			f.stats.IncrementFailed()
+			return nil, fmt.Errorf("refusing to write %s: %w", fileName, err)
 		}
+	}
 
-		if err := nginx.WriteFormatted(fileName, formatted); err != nil {
+	if f.config.SemanticCheck {
+		if err := f.nginx.CheckFile(fileName, formatted); err != nil {
+			f.stats.IncrementFailed()
+			return nil, fmt.Errorf("refusing to write %s: %w", fileName, err)
+		}
+	}
+
+	if f.config.Check {
+		original, err := os.ReadFile(fileName)
+		if err != nil {
 			f.stats.IncrementFailed()
+			return nil, err
+		}
+		expected := ""
+		if len(formatted) > 0 {
+			expected = strings.Join(formatted, "\n") + "\n"
+		}
+		if string(original) != expected {
+			f.stats.IncrementChanged()
+			log.Printf("Would reformat %s\n", fileName)
+		}
+	}
+
+	return formatted, nil
+}
+
+func (f *Formatter) writeFile(fileName string, formatted []string) error {
+	if f.config.Backup {
+		backupFile := fileName + ".bak"
+		backupContent := []byte(strings.Join(formatted, "\n") + "\n")
+		if err := os.WriteFile(backupFile, backupContent, 0o644); err != nil {
 			return err
 		}
 	}
 
-	f.stats.IncrementProcessed()
-	return nil
+	return nginx.WriteFormatted(fileName, formatted)
 }
 
 func (f *Formatter) Stats() *stats.Stats {
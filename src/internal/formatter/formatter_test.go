@@ -2,8 +2,10 @@ package formatter
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -132,3 +134,207 @@ func TestProcessDirectory(t *testing.T) {
 		t.Errorf("Expected 0 files failed, got %d", stats.FilesFailed)
 	}
 }
+
+func TestProcessFileVerify(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "unstable.conf")
+	content := "server { # main\nlisten 80;\n}\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	cfg := &config.Config{
+		IndentSize: 2,
+		Verify:     true,
+		Extensions: []string{".conf"},
+	}
+
+	f := New(cfg)
+	if err := f.processFile(path); err == nil {
+		t.Fatal("Expected verification error, got nil")
+	}
+
+	if f.Stats().FilesFailed != 1 {
+		t.Errorf("Expected 1 file failed, got %d", f.Stats().FilesFailed)
+	}
+
+	result, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(result) != content {
+		t.Errorf("File was modified despite failed verification:\n%s", result)
+	}
+}
+
+func TestProcessDirectoryNginxTest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub nginx requires a POSIX shell")
+	}
+
+	stub := filepath.Join(t.TempDir(), "nginx")
+	script := "#!/bin/sh\necho \"nginx: [emerg] unknown directive \\\"bogus\\\" in ${3}site.conf:3\" >&2\nexit 1\n"
+	if err := os.WriteFile(stub, []byte(script), 0o755); err != nil {
+		t.Fatalf("Failed to write stub nginx: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	content := "server { listen 80;\n\n\nbogus on; }\n"
+	path := filepath.Join(tmpDir, "site.conf")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var logs strings.Builder
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	cfg := &config.Config{
+		IndentSize: 2,
+		Extensions: []string{".conf"},
+		NginxTest:  stub,
+		NginxConf:  "nginx.conf",
+	}
+
+	f := New(cfg)
+	if err := f.ProcessDirectory(tmpDir); err == nil {
+		t.Fatal("Expected validation error, got nil")
+	}
+
+	result, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(result) != content {
+		t.Errorf("File was modified despite failed validation:\n%s", result)
+	}
+
+	// line 3 of the formatted output is line 4 of the original
+	if want := path + ":4:"; !strings.Contains(logs.String(), want) {
+		t.Errorf("Expected diagnostic at %s, got:\n%s", want, logs.String())
+	}
+}
+
+func TestProcessDirectoryTransactional(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		wantErr   bool
+		wantWrite bool
+	}{
+		{
+			name: "all files format",
+			files: map[string]string{
+				"a.conf": "server {\nlisten 80;\n}\n",
+				"b.conf": "server {\nlisten 443;\n}\n",
+			},
+			wantWrite: true,
+		},
+		{
+			name: "one file fails",
+			files: map[string]string{
+				"a.conf": "server {\nlisten 80;\n}\n",
+				"b.conf": "server { # main\nlisten 443;\n}\n",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0o644); err != nil {
+					t.Fatalf("Failed to create test file %s: %v", name, err)
+				}
+			}
+
+			cfg := &config.Config{
+				IndentSize:    2,
+				Verify:        true,
+				Concurrent:    true,
+				MaxWorkers:    2,
+				Transactional: true,
+				Backup:        true,
+				Extensions:    []string{".conf"},
+			}
+
+			f := New(cfg)
+			err := f.ProcessDirectory(tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProcessDirectory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for name, content := range tt.files {
+				result, err := os.ReadFile(filepath.Join(tmpDir, name))
+				if err != nil {
+					t.Fatalf("Failed to read %s: %v", name, err)
+				}
+				if changed := string(result) != content; changed != tt.wantWrite {
+					t.Errorf("%s changed = %v, want %v:\n%s", name, changed, tt.wantWrite, result)
+				}
+
+				backup, err := os.ReadFile(filepath.Join(tmpDir, name+".bak"))
+				switch {
+				case !tt.wantWrite && err == nil:
+					t.Errorf("Backup of %s written although nothing was", name)
+				case tt.wantWrite && err != nil:
+					t.Errorf("Backup of %s was not created: %v", name, err)
+				case tt.wantWrite && string(backup) != content:
+					t.Errorf("Backup of %s =\n%s\nwant the original\n%s", name, backup, content)
+				}
+			}
+
+			leftovers, _ := filepath.Glob(filepath.Join(tmpDir, "*"+stagedSuffix))
+			if len(leftovers) != 0 {
+				t.Errorf("Staged files left behind: %v", leftovers)
+			}
+		})
+	}
+}
+
+func TestProcessDirectoryEntry(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"nginx.conf":             "http {\ninclude sites-enabled/*;\n}\n",
+		"sites-enabled/example":  "server {\nlisten 80;\n}\n",
+		"sites-available/unused": "server {\nlisten 81;\n}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create test file %s: %v", name, err)
+		}
+	}
+
+	cfg := &config.Config{
+		IndentSize: 2,
+		Concurrent: true,
+		MaxWorkers: 2,
+		Entries:    []string{"nginx.conf"},
+	}
+
+	f := New(cfg)
+	if err := f.ProcessDirectory(tmpDir); err != nil {
+		t.Fatalf("ProcessDirectory() error = %v", err)
+	}
+
+	stats := f.Stats()
+	if stats.FilesProcessed != 2 {
+		t.Errorf("Expected 2 files processed, got %d", stats.FilesProcessed)
+	}
+	if stats.FilesSkipped != 1 {
+		t.Errorf("Expected 1 file skipped, got %d", stats.FilesSkipped)
+	}
+
+	result, err := os.ReadFile(filepath.Join(tmpDir, "sites-enabled/example"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(result) != "server {\n  listen 80;\n}\n" {
+		t.Errorf("Included file without extension was not formatted:\n%s", result)
+	}
+}
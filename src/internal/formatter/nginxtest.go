@@ -0,0 +1,39 @@
+package formatter
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/internal/validate"
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// validate runs nginx -t against the formatted tree and logs its
+// diagnostics against the original files
+func (f *Formatter) validate(directory string, formatted map[string][]string) error {
+	v := validate.New(f.config.NginxTest, f.config.NginxConf)
+	diagnostics, err := v.Validate(directory, formatted)
+	for _, d := range diagnostics {
+		if lines, ok := formatted[d.File]; ok {
+			d.Line = f.originalLine(d.File, lines, d.Line)
+		}
+		log.Printf("nginx: %s\n", d)
+	}
+	return err
+}
+
+// originalLine maps a line of formatted output back to the file on disk
+func (f *Formatter) originalLine(fileName string, formatted []string, line int) int {
+	original, err := os.Open(fileName)
+	if err != nil {
+		return line
+	}
+	defer original.Close()
+
+	m, err := nginx.NewLineMap(original, strings.NewReader(strings.Join(formatted, "\n")), f.config.RemoveComments)
+	if err != nil {
+		return line
+	}
+	return m.Original(line)
+}
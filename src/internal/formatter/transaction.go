@@ -0,0 +1,146 @@
+package formatter
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// stagedSuffix is appended to a file name while its new content waits to
+// be renamed into place
+const stagedSuffix = ".gofmtnginx-staged"
+
+// processDirectoryStaged formats every file into memory before writing
+// anything. The tree is validated with nginx -t when configured, and in
+// transactional mode any failure leaves every file on disk unchanged.
+func (f *Formatter) processDirectoryStaged(directory string) error {
+	var mu sync.Mutex
+	var failed int
+	formatted := make(map[string][]string)
+
+	err := f.walk(directory, func(path string) error {
+		lines, err := f.formatFile(path)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			failed++
+			return err
+		}
+		formatted[path] = lines
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if f.config.Transactional && failed > 0 {
+		f.abort(formatted)
+		return fmt.Errorf("%d file(s) failed to format, no files were written", failed)
+	}
+
+	if f.config.NginxTest != "" {
+		if err := f.validate(directory, formatted); err != nil {
+			f.abort(formatted)
+			return fmt.Errorf("validation failed, no files were written: %w", err)
+		}
+	}
+
+	if f.config.DryRun {
+		for range formatted {
+			f.stats.IncrementProcessed()
+		}
+		return nil
+	}
+
+	if f.config.Transactional {
+		if err := f.commit(formatted); err != nil {
+			f.abort(formatted)
+			return fmt.Errorf("commit failed, no files were changed: %w", err)
+		}
+		for range formatted {
+			f.stats.IncrementProcessed()
+		}
+		return nil
+	}
+
+	for path, lines := range formatted {
+		if err := f.writeFile(path, lines); err != nil {
+			f.stats.IncrementFailed()
+			log.Printf("Error processing file %s: %v\n", path, err)
+			continue
+		}
+		f.stats.IncrementProcessed()
+	}
+
+	return nil
+}
+
+// abort counts every formatted file as failed because none will be written
+func (f *Formatter) abort(formatted map[string][]string) {
+	for range formatted {
+		f.stats.IncrementFailed()
+	}
+}
+
+// commit writes every file to a staged sibling, backs up the originals
+// when configured and then renames them all into place. If staging fails the staged files are removed; if a rename
+// fails the files already renamed are restored from their original content.
+func (f *Formatter) commit(formatted map[string][]string) error {
+	type staged struct {
+		path     string
+		original []byte
+		mode     os.FileMode
+	}
+	var files []staged
+
+	cleanup := func() {
+		for _, s := range files {
+			os.Remove(s.path + stagedSuffix)
+		}
+	}
+
+	for path, lines := range formatted {
+		info, err := os.Stat(path)
+		if err != nil {
+			cleanup()
+			return err
+		}
+		original, err := os.ReadFile(path)
+		if err != nil {
+			cleanup()
+			return err
+		}
+
+		files = append(files, staged{path: path, original: original, mode: info.Mode().Perm()})
+		if err := os.WriteFile(path+stagedSuffix, []byte(strings.Join(lines, "\n")+"\n"), info.Mode().Perm()); err != nil {
+			cleanup()
+			return fmt.Errorf("error staging %s: %w", path, err)
+		}
+	}
+
+	if f.config.Backup {
+		for _, s := range files {
+			if err := os.WriteFile(s.path+".bak", s.original, 0o644); err != nil {
+				cleanup()
+				return fmt.Errorf("error writing backup for %s: %w", s.path, err)
+			}
+		}
+	}
+
+	for i, s := range files {
+		if err := os.Rename(s.path+stagedSuffix, s.path); err != nil {
+			for _, done := range files[:i] {
+				if restoreErr := os.WriteFile(done.path, done.original, done.mode); restoreErr != nil {
+					err = errors.Join(err, fmt.Errorf("error restoring %s: %w", done.path, restoreErr))
+				}
+			}
+			cleanup()
+			return fmt.Errorf("error renaming %s: %w", s.path, err)
+		}
+	}
+
+	return nil
+}
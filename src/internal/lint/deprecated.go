@@ -0,0 +1,223 @@
+package lint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// Deprecation records the nginx versions in which a directive, or one of
+// its parameters when Parameter is set, was deprecated and removed. Either
+// version may be empty.
+type Deprecation struct {
+	Directive   string `json:"directive"`
+	Parameter   string `json:"parameter,omitempty"`
+	Deprecated  string `json:"deprecated,omitempty"`
+	Removed     string `json:"removed,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+
+	deprecated, removed Version
+}
+
+func (dep *Deprecation) parse() error {
+	if dep.Directive == "" {
+		return fmt.Errorf("deprecation without a directive")
+	}
+	if dep.Deprecated == "" && dep.Removed == "" {
+		return fmt.Errorf("deprecation of %q has no version", dep.Directive)
+	}
+
+	var err error
+	if dep.Deprecated != "" {
+		if dep.deprecated, err = ParseVersion(dep.Deprecated); err != nil {
+			return fmt.Errorf("deprecation of %q: %w", dep.Directive, err)
+		}
+	}
+	if dep.Removed != "" {
+		if dep.removed, err = ParseVersion(dep.Removed); err != nil {
+			return fmt.Errorf("deprecation of %q: %w", dep.Directive, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether dep applies to d
+func (dep *Deprecation) matches(d *nginx.Directive) bool {
+	return dep.Directive == d.Name && (dep.Parameter == "" || containsString(d.Args, dep.Parameter))
+}
+
+// subject names what is deprecated in messages
+func (dep *Deprecation) subject() string {
+	if dep.Parameter == "" {
+		return fmt.Sprintf("%q", dep.Directive)
+	}
+	return fmt.Sprintf("the %q parameter of %q", dep.Parameter, dep.Directive)
+}
+
+// Version is an nginx version such as 1.25.1. A version with fewer parts,
+// such as 1.25, stands for the newest release in that series.
+type Version []int
+
+// ParseVersion parses a dotted nginx version
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	for _, part := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid nginx version %q", s)
+		}
+		v = append(v, n)
+	}
+	return v, nil
+}
+
+// AtLeast reports whether v is the same as or newer than other. A nil v
+// stands for the newest version of all.
+func (v Version) AtLeast(other Version) bool {
+	if v == nil {
+		return true
+	}
+	for i := range other {
+		if i >= len(v) {
+			return true
+		}
+		if v[i] != other[i] {
+			return v[i] > other[i]
+		}
+	}
+	return true
+}
+
+func (v Version) String() string {
+	parts := make([]string, len(v))
+	for i, n := range v {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ".")
+}
+
+func init() {
+	Register(&rule{
+		id:          "deprecated",
+		description: "directives and parameters that are deprecated or removed in the target nginx version",
+		severity:    Warning,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			var diagnostics []Diagnostic
+			target := ctx.Tree.Target
+			for _, dep := range ctx.Tree.Schema.Deprecations {
+				if !dep.matches(d) {
+					continue
+				}
+
+				var diag Diagnostic
+				switch {
+				case dep.removed != nil && target.AtLeast(dep.removed):
+					diag = r.report(d, "%s was removed in nginx %s", dep.subject(), dep.removed)
+					diag.Severity = Error
+				case dep.deprecated != nil && target.AtLeast(dep.deprecated):
+					diag = r.report(d, "%s is deprecated since nginx %s", dep.subject(), dep.deprecated)
+				default:
+					continue
+				}
+
+				diag.Suggestion = dep.Replacement
+				diagnostics = append(diagnostics, diag)
+			}
+			return diagnostics
+		},
+		fix: func(r *rule, d *nginx.Directive, ctx *Context, e *Editor) error {
+			switch {
+			case d.Name == "listen" && containsString(d.Args, "http2"):
+				return fixListenHTTP2(d, ctx, e)
+			case d.Name == "ssl" && ctx.Name == "server":
+				return fixSSLOn(d, ctx, e)
+			}
+			return nil
+		},
+	})
+}
+
+// fixListenHTTP2 moves the http2 parameter of listen to "http2 on" in the
+// enclosing server
+func fixListenHTTP2(d *nginx.Directive, ctx *Context, e *Editor) error {
+	server := ctx.Enclosing("server")
+	if server == nil {
+		return nil
+	}
+
+	doc, node, err := e.Node(d)
+	if err != nil {
+		return err
+	}
+	var args []string
+	for _, arg := range node.Args {
+		if arg != "http2" {
+			args = append(args, arg)
+		}
+	}
+	doc.SetArgs(node, args...)
+
+	if hasChild(ctx.Tree.Children(server), "http2") {
+		return nil
+	}
+	serverDoc, serverNode, err := e.Node(server)
+	if err != nil {
+		return err
+	}
+	if hasChild(serverNode.Block, "http2") {
+		// added for an earlier listen of the same server
+		return nil
+	}
+	_, err = serverDoc.Add(serverNode, "http2", "on")
+	return err
+}
+
+// fixSSLOn replaces "ssl on" in a server with the ssl parameter on each of
+// its listen directives, and drops "ssl off". A server relying on the
+// default listen address is left alone.
+func fixSSLOn(d *nginx.Directive, ctx *Context, e *Editor) error {
+	if len(d.Args) != 1 {
+		return nil
+	}
+
+	if strings.EqualFold(d.Args[0], "on") {
+		var listens []*nginx.Directive
+		for _, child := range ctx.Tree.Children(ctx.Parent()) {
+			if child.Name == "listen" && len(child.Args) > 0 {
+				listens = append(listens, child)
+			}
+		}
+		if len(listens) == 0 {
+			return nil
+		}
+
+		for _, listen := range listens {
+			if containsString(listen.Args, "ssl") {
+				continue
+			}
+			doc, node, err := e.Node(listen)
+			if err != nil {
+				return err
+			}
+			args := append([]string{node.Args[0], "ssl"}, node.Args[1:]...)
+			doc.SetArgs(node, args...)
+		}
+	}
+
+	doc, node, err := e.Node(d)
+	if err != nil {
+		return err
+	}
+	return doc.Remove(node)
+}
+
+func hasChild(directives []*nginx.Directive, name string) bool {
+	for _, d := range directives {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
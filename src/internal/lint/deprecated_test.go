@@ -0,0 +1,87 @@
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		v, other string
+		want     bool
+	}{
+		{"1.25.1", "1.25.1", true},
+		{"1.25.0", "1.25.1", false},
+		{"1.25", "1.25.1", true},
+		{"1.24.9", "1.25.1", false},
+		{"2.0", "1.25.1", true},
+		{"1.9.10", "1.9.5", true},
+	}
+
+	for _, tt := range tests {
+		v, err := ParseVersion(tt.v)
+		if err != nil {
+			t.Fatalf("ParseVersion(%q) error = %v", tt.v, err)
+		}
+		other, _ := ParseVersion(tt.other)
+		if got := v.AtLeast(other); got != tt.want {
+			t.Errorf("Version(%s).AtLeast(%s) = %v, want %v", tt.v, tt.other, got, tt.want)
+		}
+	}
+
+	if _, err := ParseVersion("1.x"); err == nil {
+		t.Errorf("ParseVersion(1.x) did not return an error")
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf": "http {\n  server {\n    listen 443 ssl http2;\n    ssl on;\n    ssl_protocols TLSv1 TLSv1.2;\n    spdy_chunk_size 8k;\n  }\n}\n",
+	})
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{
+			target: "",
+			want:   []string{"3 warning http2 on", "4 error listen 443 ssl", "5 warning TLSv1.2 TLSv1.3", "6 error http2_chunk_size"},
+		},
+		{
+			target: "1.25",
+			want:   []string{"3 warning http2 on", "4 error listen 443 ssl", "5 warning TLSv1.2 TLSv1.3", "6 error http2_chunk_size"},
+		},
+		{
+			target: "1.22",
+			want:   []string{"4 warning listen 443 ssl", "6 error http2_chunk_size"},
+		},
+		{
+			target: "1.8",
+			want:   nil,
+		},
+	}
+
+	l, err := New([]string{"deprecated"}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			tree := Load("", filepath.Join(root, "nginx.conf"))
+			if tt.target != "" {
+				tree.Target, _ = ParseVersion(tt.target)
+			}
+
+			var got []string
+			for _, d := range l.Lint(tree) {
+				got = append(got, fmt.Sprintf("%d %s %s", d.Line, d.Severity, d.Suggestion))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
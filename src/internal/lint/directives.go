@@ -0,0 +1,147 @@
+package lint
+
+import (
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// inKnownBlock reports whether the schema knows the block d appears in, so
+// its context can be trusted. Blocks of unknown modules are not checked.
+func inKnownBlock(ctx *Context) bool {
+	parent := ctx.Parent()
+	return parent == nil || ctx.Tree.Schema.Known(parent.Name)
+}
+
+func init() {
+	Register(&rule{
+		id:          "unknown-directive",
+		description: "directives the schema does not know; describe third-party modules with -schema",
+		severity:    Info,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			if ctx.Tree.Schema.Known(d.Name) || !inKnownBlock(ctx) {
+				return nil
+			}
+			return []Diagnostic{r.report(d, "unknown directive %q", d.Name)}
+		},
+	})
+
+	Register(&rule{
+		id:          "context",
+		description: "directives used in a context nginx does not allow them in",
+		severity:    Error,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			s := ctx.Tree.Schema
+			if !s.Known(d.Name) || !inKnownBlock(ctx) || s.Spec(d.Name, ctx.Name) != nil {
+				return nil
+			}
+			return []Diagnostic{r.report(d, "%q is not allowed in %s, only in %s",
+				d.Name, ctx.Name, strings.Join(s.Contexts(d.Name), ", "))}
+		},
+	})
+
+	Register(&rule{
+		id:          "arity",
+		description: "directives with the wrong number of arguments, or a block where none belongs",
+		severity:    Error,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			spec := ctx.Tree.Schema.Spec(d.Name, ctx.Name)
+			switch {
+			case spec == nil:
+				return nil
+			case spec.Block && !d.IsBlock():
+				return []Diagnostic{r.report(d, "%q has no opening \"{\"", d.Name)}
+			case !spec.Block && d.IsBlock():
+				return []Diagnostic{r.report(d, "%q does not take a block", d.Name)}
+			}
+			if problem := spec.checkArgs(d.Args); problem != "" {
+				return []Diagnostic{r.report(d, "%q %s", d.Name, problem)}
+			}
+			return nil
+		},
+	})
+
+	Register(&rule{
+		id:          "duplicate",
+		description: "directives that may appear only once in a block but are repeated",
+		severity:    Error,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			if !single(d, ctx) {
+				return nil
+			}
+			for _, sibling := range ctx.Siblings() {
+				if sibling == d {
+					return nil
+				}
+				if sibling.Name == d.Name {
+					return []Diagnostic{r.report(d, "%q is duplicate, first set at %s", d.Name, position{sibling.File, sibling.Line})}
+				}
+			}
+			return nil
+		},
+		fix: func(r *rule, d *nginx.Directive, ctx *Context, e *Editor) error {
+			// only an exact repeat can go without changing the meaning
+			if firstCopy(d, ctx) == nil {
+				return nil
+			}
+			doc, node, err := e.Node(d)
+			if err != nil {
+				return err
+			}
+			return doc.Remove(node)
+		},
+	})
+
+	Register(&rule{
+		id:          "exact-duplicate",
+		description: "directives that may be repeated but appear twice in the same block with the same arguments",
+		severity:    Warning,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			// a repeat of a directive allowed once is reported by duplicate
+			if single(d, ctx) {
+				return nil
+			}
+			if first := firstCopy(d, ctx); first != nil {
+				return []Diagnostic{r.report(d, "%q repeats %s exactly", d.Name, position{first.File, first.Line})}
+			}
+			return nil
+		},
+		fix: func(r *rule, d *nginx.Directive, ctx *Context, e *Editor) error {
+			doc, node, err := e.Node(d)
+			if err != nil {
+				return err
+			}
+			return doc.Remove(node)
+		},
+	})
+}
+
+// single reports whether the schema allows d only once in its block
+func single(d *nginx.Directive, ctx *Context) bool {
+	spec := ctx.Tree.Schema.Spec(d.Name, ctx.Name)
+	return spec != nil && !spec.Multiple
+}
+
+// identical reports whether a and b are the same simple directive
+func identical(a, b *nginx.Directive) bool {
+	return a.Name == b.Name && !a.IsBlock() && !b.IsBlock() &&
+		strings.Join(a.Args, "\x00") == strings.Join(b.Args, "\x00")
+}
+
+// firstCopy returns an earlier directive in d's block that d repeats
+// exactly. Includes are left alone since including a file twice can be
+// deliberate.
+func firstCopy(d *nginx.Directive, ctx *Context) *nginx.Directive {
+	if d.Name == "include" || d.IsBlock() {
+		return nil
+	}
+	for _, sibling := range ctx.Siblings() {
+		if sibling == d {
+			return nil
+		}
+		if identical(sibling, d) {
+			return sibling
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// Fixer is implemented by rules that can rewrite the configuration to
+// resolve what they report. Fix is only called for directives Check
+// reported and that are not covered by a disable comment.
+type Fixer interface {
+	Rule
+	Fix(d *nginx.Directive, ctx *Context, e *Editor) error
+}
+
+// TreeFixer is implemented by tree rules that fix the text of files
+// before they are parsed, such as inserting missing semicolons
+type TreeFixer interface {
+	TreeRule
+	FixTree(t *Tree, e *Editor) error
+}
+
+// Editor collects the edits made by fixes. Edits to the text of a file
+// and to its directives are kept in memory until Save.
+type Editor struct {
+	// Formatter lays out new and changed directives. Nil keeps the
+	// indentation each file already uses.
+	Formatter *nginx.Formatter
+
+	original map[string][]byte
+	text     map[string][]byte
+	docs     map[string]*nginx.Document
+}
+
+// NewEditor returns an empty Editor
+func NewEditor() *Editor {
+	return &Editor{
+		original: make(map[string][]byte),
+		text:     make(map[string][]byte),
+		docs:     make(map[string]*nginx.Document),
+	}
+}
+
+// Text returns the current content of a file
+func (e *Editor) Text(file string) ([]byte, error) {
+	if doc, ok := e.docs[file]; ok {
+		return doc.Bytes()
+	}
+	if text, ok := e.text[file]; ok {
+		return text, nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error opening input file: %w", err)
+	}
+	e.original[file] = content
+	return content, nil
+}
+
+// SetText replaces the content of a file. It must not be called for a
+// file whose directives have been edited.
+func (e *Editor) SetText(file string, content []byte) error {
+	if _, ok := e.docs[file]; ok {
+		return fmt.Errorf("%s: text edited after its directives", file)
+	}
+	if _, err := e.Text(file); err != nil {
+		return err
+	}
+	e.text[file] = content
+	return nil
+}
+
+// Node returns the document a directive from the Tree passed to Fix
+// belongs to. That tree is loaded from the editor's documents, so the
+// directive is itself the node to edit.
+func (e *Editor) Node(d *nginx.Directive) (*nginx.Document, *nginx.Directive, error) {
+	doc, ok := e.docs[d.File]
+	if !ok || !doc.Contains(d) {
+		return nil, nil, fmt.Errorf("%s:%d: could not locate %q for editing", d.File, d.Line, d.Name)
+	}
+	return doc, d, nil
+}
+
+// load makes a document of every file in t and has t's graph hold the
+// documents' directives
+func (e *Editor) load(t *Tree) error {
+	for _, file := range t.Graph.Files {
+		content, err := e.Text(file)
+		if err != nil {
+			return err
+		}
+		doc, err := nginx.NewDocument(file, content)
+		if err != nil {
+			return err
+		}
+		if e.Formatter != nil {
+			doc.Formatter = e.Formatter
+		}
+		e.docs[file] = doc
+		t.Graph.Configs[file] = doc.Directives
+	}
+	return nil
+}
+
+// overlay returns the current content of every file edited as text
+func (e *Editor) overlay() map[string][]byte {
+	overlay := make(map[string][]byte, len(e.text))
+	for file, text := range e.text {
+		overlay[file] = text
+	}
+	return overlay
+}
+
+// Overlay returns the current content of every changed file, for loading
+// the result of the edits with Tree.Reload
+func (e *Editor) Overlay() map[string][]byte {
+	overlay := make(map[string][]byte)
+	for _, file := range e.Changed() {
+		overlay[file], _ = e.Text(file)
+	}
+	return overlay
+}
+
+// Changed returns the files whose content differs from the disk, sorted
+func (e *Editor) Changed() []string {
+	var files []string
+	for file := range e.original {
+		if text, _ := e.Text(file); !bytes.Equal(text, e.original[file]) {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Original returns the content of a file as it was on disk
+func (e *Editor) Original(file string) []byte {
+	return e.original[file]
+}
+
+// Save writes every changed file, keeping its mode
+func (e *Editor) Save() error {
+	for _, file := range e.Changed() {
+		text, err := e.Text(file)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(0o644)
+		if info, err := os.Stat(file); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := os.WriteFile(file, text, mode); err != nil {
+			return fmt.Errorf("error writing %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// Fix applies the fixes of the linter's rules to t and returns the edits
+// without writing them. Text fixes run first; the tree is then loaded
+// again from their result so the other fixes see it.
+func (l *Linter) Fix(t *Tree) (*Editor, error) {
+	e := NewEditor()
+	e.Formatter = l.Formatter
+
+	for _, r := range l.Rules {
+		if tf, ok := r.(TreeFixer); ok {
+			if err := tf.FixTree(t, e); err != nil {
+				return nil, err
+			}
+		}
+	}
+	t = t.Reload(e.overlay())
+	if err := e.load(t); err != nil {
+		return nil, err
+	}
+
+	suppressed := suppressions(t)
+	// a file included twice yields the same directive twice
+	fixed := make(map[*nginx.Directive]map[string]bool)
+	var err error
+	t.Walk(func(d *nginx.Directive, ctx *Context) {
+		for _, r := range l.Rules {
+			fixer, ok := r.(Fixer)
+			if err != nil || !ok || !appliesTo(r, ctx.Name) || fixed[d][r.ID()] {
+				continue
+			}
+			if fixed[d] == nil {
+				fixed[d] = make(map[string]bool)
+			}
+			fixed[d][r.ID()] = true
+
+			for _, diag := range r.Check(d, ctx) {
+				if !suppressed.covers(diag) {
+					err = fixer.Fix(d, ctx, e)
+					break
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
@@ -0,0 +1,144 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFix(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		rules []string
+		want  map[string]string
+	}{
+		{
+			name:  "listen http2",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    listen 443 ssl http2;\n    listen [::]:443 ssl http2;\n  }\n}\n"},
+			rules: []string{"deprecated"},
+			want:  map[string]string{"nginx.conf": "http {\n  server {\n    listen 443 ssl;\n    listen [::]:443 ssl;\n    http2 on;\n  }\n}\n"},
+		},
+		{
+			name:  "listen http2 on one line",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    listen 443 ssl http2; listen [::]:443 ssl http2;\n  }\n}\n"},
+			rules: []string{"deprecated"},
+			want:  map[string]string{"nginx.conf": "http {\n  server {\n    listen 443 ssl;\n    listen [::]:443 ssl;\n    http2 on;\n  }\n}\n"},
+		},
+		{
+			name:  "keeps indentation and quoting",
+			files: map[string]string{"nginx.conf": "http {\n    server { listen \"443\" ssl http2; add_header Strict-Transport-Security \"max-age=1\"; }\n}\n"},
+			rules: []string{"deprecated"},
+			want:  map[string]string{"nginx.conf": "http {\n    server {\n        listen \"443\" ssl;\n        add_header Strict-Transport-Security \"max-age=1\";\n        http2 on;\n    }\n}\n"},
+		},
+		{
+			name:  "http2 already on",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    http2 on;\n    listen 443 ssl http2;\n  }\n}\n"},
+			rules: []string{"deprecated"},
+			want:  map[string]string{"nginx.conf": "http {\n  server {\n    http2 on;\n    listen 443 ssl;\n  }\n}\n"},
+		},
+		{
+			name:  "ssl on",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    listen 443;\n    ssl on;\n  }\n}\n"},
+			rules: []string{"deprecated"},
+			want:  map[string]string{"nginx.conf": "http {\n  server {\n    listen 443 ssl;\n  }\n}\n"},
+		},
+		{
+			name:  "missing semicolons",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    root /srv\n    index index.html;\n    location / {\n      try_files $uri =404 # keep\n    }\n  }\n}\n"},
+			rules: []string{"missing-semicolon"},
+			want:  map[string]string{"nginx.conf": "http {\n  server {\n    root /srv;\n    index index.html;\n    location / {\n      try_files $uri =404; # keep\n    }\n  }\n}\n"},
+		},
+		{
+			name: "exact duplicate in include",
+			files: map[string]string{
+				"nginx.conf":   "http {\n  include headers.conf;\n  add_header X-A b;\n  add_header X-A b;\n  add_header X-A c;\n}\n",
+				"headers.conf": "add_header X-B c;\nadd_header X-B c;\n",
+			},
+			rules: []string{"exact-duplicate"},
+			want: map[string]string{
+				"nginx.conf":   "http {\n  include headers.conf;\n  add_header X-A b;\n  add_header X-A c;\n}\n",
+				"headers.conf": "add_header X-B c;\n",
+			},
+		},
+		{
+			name: "identical duplicate",
+			files: map[string]string{
+				"nginx.conf": "http {\n  include gzip.conf;\n  root /a;\n  root /b;\n}\n",
+				"gzip.conf":  "gzip on;\ngzip on;\n",
+			},
+			rules: []string{"duplicate"},
+			want: map[string]string{
+				"nginx.conf": "http {\n  include gzip.conf;\n  root /a;\n  root /b;\n}\n",
+				"gzip.conf":  "gzip on;\n",
+			},
+		},
+		{
+			name:  "disabled",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    ssl on; # gofmtnginx:disable=deprecated\n  }\n}\n"},
+			rules: []string{"deprecated"},
+			want:  map[string]string{"nginx.conf": "http {\n  server {\n    ssl on; # gofmtnginx:disable=deprecated\n  }\n}\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, tt.files)
+			entry := filepath.Join(root, "nginx.conf")
+			l, err := New(tt.rules, nil)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			e, err := l.Fix(Load("", entry))
+			if err != nil {
+				t.Fatalf("Fix() error = %v", err)
+			}
+			if err := e.Save(); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			for name, want := range tt.want {
+				got, err := os.ReadFile(filepath.Join(root, name))
+				if err != nil {
+					t.Fatalf("Failed to read %s: %v", name, err)
+				}
+				if string(got) != want {
+					t.Errorf("%s after Fix() =\n%s\nwant\n%s", name, got, want)
+				}
+			}
+
+			// a second run finds nothing left to fix
+			e, err = l.Fix(Load("", entry))
+			if err != nil {
+				t.Fatalf("second Fix() error = %v", err)
+			}
+			if changed := e.Changed(); len(changed) > 0 {
+				t.Errorf("second Fix() changed %v", changed)
+			}
+		})
+	}
+}
+
+func TestFixDryRun(t *testing.T) {
+	root := writeTree(t, map[string]string{"nginx.conf": "http {\n  server {\n    ssl on;\n    listen 443\n  }\n}\n"})
+	entry := filepath.Join(root, "nginx.conf")
+	l, err := New([]string{"deprecated", "missing-semicolon"}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	e, err := l.Fix(Load("", entry))
+	if err != nil {
+		t.Fatalf("Fix() error = %v", err)
+	}
+	want := "http {\n  server {\n    listen 443 ssl;\n  }\n}\n"
+	if got, _ := e.Text(entry); string(got) != want {
+		t.Errorf("Text() =\n%s\nwant\n%s", got, want)
+	}
+	if content, _ := os.ReadFile(entry); string(content) != string(e.Original(entry)) {
+		t.Errorf("Fix() wrote %s before Save()", entry)
+	}
+	if remaining := l.Lint(Load("", entry).Reload(e.Overlay())); len(remaining) > 0 {
+		t.Errorf("Lint() after Fix() = %v, want none", remaining)
+	}
+}
@@ -0,0 +1,99 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// headerBlocks are the blocks add_header may appear in
+var headerBlocks = []string{"http", "server", "location", "if"}
+
+// headers returns the add_header directives of a block
+func headers(t *Tree, block *nginx.Directive) []*nginx.Directive {
+	var found []*nginx.Directive
+	for _, child := range t.Children(block) {
+		if child.Name == "add_header" {
+			found = append(found, child)
+		}
+	}
+	return found
+}
+
+// effectiveHeaders returns the add_header directives that apply in the
+// innermost of blocks. A block inherits the headers of the one around it
+// only if it has none of its own.
+func effectiveHeaders(t *Tree, blocks []*nginx.Directive) []*nginx.Directive {
+	var effective []*nginx.Directive
+	for _, block := range blocks {
+		if !containsString(headerBlocks, block.Name) {
+			continue
+		}
+		if own := headers(t, block); len(own) > 0 {
+			effective = own
+		}
+	}
+	return effective
+}
+
+// setsHeader reports whether any of the add_header directives sets name
+func setsHeader(directives []*nginx.Directive, name string) bool {
+	for _, d := range directives {
+		if len(d.Args) > 0 && strings.EqualFold(d.Args[0], name) {
+			return true
+		}
+	}
+	return false
+}
+
+// LocationHeaders is the set of response headers a location adds
+type LocationHeaders struct {
+	Location *nginx.Directive
+	// Headers are the add_header directives in effect, wherever they are
+	Headers []*nginx.Directive
+}
+
+// EffectiveHeaders returns the headers each location of the http servers
+// adds, in configuration order, after nginx's inheritance rules
+func EffectiveHeaders(t *Tree) []LocationHeaders {
+	var found []LocationHeaders
+	t.Walk(func(d *nginx.Directive, ctx *Context) {
+		if d.Name != "location" || ctx.Enclosing("http") == nil {
+			return
+		}
+		blocks := append(ctx.Parents[:len(ctx.Parents):len(ctx.Parents)], d)
+		found = append(found, LocationHeaders{Location: d, Headers: effectiveHeaders(t, blocks)})
+	})
+	return found
+}
+
+func init() {
+	Register(&rule{
+		id:          "add-header-inheritance",
+		description: "blocks whose add_header directives hide those of the enclosing block, which nginx then drops",
+		severity:    Warning,
+		contexts:    []string{"http", "server", "location"},
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			if !d.IsBlock() || !containsString(headerBlocks, d.Name) {
+				return nil
+			}
+			own := headers(ctx.Tree, d)
+			if len(own) == 0 {
+				return nil
+			}
+
+			var lost []string
+			for _, h := range effectiveHeaders(ctx.Tree, ctx.Parents) {
+				if len(h.Args) > 0 && !setsHeader(own, h.Args[0]) {
+					lost = append(lost, fmt.Sprintf("%s (%s:%d)", h.Args[0], h.File, h.Line))
+				}
+			}
+			if len(lost) == 0 {
+				return nil
+			}
+			return []Diagnostic{r.report(d, "%s sets add_header, so it does not inherit %s",
+				strings.TrimSpace(d.Name+" "+strings.Join(d.Args, " ")), strings.Join(lost, ", "))}
+		},
+	})
+}
@@ -0,0 +1,84 @@
+package lint
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAddHeaderInheritance(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name:  "location shadows server",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    add_header X-A 1;\n    location / {\n      add_header X-B 2;\n    }\n  }\n}\n"},
+			want:  []string{"nginx.conf:4 add-header-inheritance"},
+		},
+		{
+			name:  "location repeats the parent headers",
+			files: map[string]string{"nginx.conf": "http {\n  add_header X-A 1;\n  server {\n    location / {\n      add_header X-A 1;\n      add_header X-B 2;\n    }\n  }\n}\n"},
+		},
+		{
+			name:  "inherited through a block without headers",
+			files: map[string]string{"nginx.conf": "http {\n  add_header X-A 1;\n  server {\n    location / {\n      add_header X-B 2;\n    }\n  }\n}\n"},
+			want:  []string{"nginx.conf:4 add-header-inheritance"},
+		},
+		{
+			name: "headers from includes",
+			files: map[string]string{
+				"nginx.conf":   "http {\n  include headers.conf;\n  server {\n    include api.conf;\n  }\n}\n",
+				"headers.conf": "add_header X-A 1;\n",
+				"api.conf":     "add_header X-B 2;\n",
+			},
+			want: []string{"nginx.conf:3 add-header-inheritance"},
+		},
+		{
+			name:  "if in location",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    location / {\n      add_header X-A 1;\n      if ($x) {\n        add_header X-B 2;\n      }\n    }\n  }\n}\n"},
+			want:  []string{"nginx.conf:5 add-header-inheritance"},
+		},
+		{
+			name:  "no headers in the child",
+			files: map[string]string{"nginx.conf": "http {\n  add_header X-A 1;\n  server {\n    location / {\n    }\n  }\n}\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintFiles(t, tt.files, "add-header-inheritance")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEffectiveHeaders(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf": "http {\n  add_header X-A 1;\n  server {\n    location / {\n      location /nested/ {\n      }\n    }\n    location /api/ {\n      include api.conf;\n    }\n  }\n}\n",
+		"api.conf":   "add_header X-B 2;\nadd_header X-C 3;\n",
+	})
+
+	got := make(map[string]string)
+	for _, lh := range EffectiveHeaders(Load("", filepath.Join(root, "nginx.conf"))) {
+		var names []string
+		for _, h := range lh.Headers {
+			names = append(names, h.Args[0]+"@"+filepath.Base(h.File)+":"+strconv.Itoa(h.Line))
+		}
+		got[lh.Location.Args[0]] = strings.Join(names, " ")
+	}
+
+	want := map[string]string{
+		"/":        "X-A@nginx.conf:2",
+		"/nested/": "X-A@nginx.conf:2",
+		"/api/":    "X-B@api.conf:1 X-C@api.conf:2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EffectiveHeaders() = %v, want %v", got, want)
+	}
+}
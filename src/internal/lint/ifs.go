@@ -0,0 +1,104 @@
+package lint
+
+import (
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+const (
+	ifIsEvilURL = "https://github.com/nginxinc/nginx-wiki/blob/master/source/start/topics/depth/ifisevil.rst"
+	mapURL      = "https://nginx.org/en/docs/http/ngx_http_map_module.html"
+)
+
+// comparisons are the operators of if conditions that compare a variable
+// with a string or regular expression
+var comparisons = []string{"=", "!=", "~", "~*", "!~", "!~*"}
+
+// testedVariable returns the variable an if directive compares, or "" if
+// its condition is not a comparison
+func testedVariable(d *nginx.Directive) string {
+	if len(d.Args) != 3 || !strings.HasPrefix(d.Args[0], "$") || !containsString(comparisons, d.Args[1]) {
+		return ""
+	}
+	return d.Args[0]
+}
+
+// onlySets reports whether a block does nothing but set variables
+func onlySets(t *Tree, d *nginx.Directive) bool {
+	children := t.Children(d)
+	sets := 0
+	for _, child := range children {
+		switch {
+		case child.IsComment():
+		case child.Name == "set":
+			sets++
+		default:
+			return false
+		}
+	}
+	return sets > 0
+}
+
+func init() {
+	Register(&rule{
+		id:          "if-in-location",
+		description: "directives other than return and rewrite ... last inside if in a location, which behave unpredictably",
+		severity:    Warning,
+		contexts:    []string{"if in location"},
+		url:         ifIsEvilURL,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			switch {
+			case d.Name == "return", d.Name == "if":
+				return nil
+			case d.Name == "rewrite" && len(d.Args) > 0 && d.Args[len(d.Args)-1] == "last":
+				return nil
+			}
+			return []Diagnostic{r.report(d, "only return and rewrite ... last are safe inside if in a location, not %q", d.Name)}
+		},
+	})
+
+	Register(&rule{
+		id:          "nested-if",
+		description: "if blocks inside other if blocks, which nginx does not allow",
+		severity:    Error,
+		url:         ifIsEvilURL,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			if d.Name != "if" || ctx.Enclosing("if") == nil {
+				return nil
+			}
+			return []Diagnostic{r.report(d, "if cannot be nested in another if; combine the conditions with a map")}
+		},
+	})
+
+	Register(&rule{
+		id:          "if-to-map",
+		description: "if blocks that test a variable where a map would be clearer and cheaper",
+		severity:    Info,
+		contexts:    []string{"server", "location"},
+		url:         mapURL,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			variable := ""
+			if d.Name == "if" {
+				variable = testedVariable(d)
+			}
+			if variable == "" {
+				return nil
+			}
+
+			var tests []*nginx.Directive
+			for _, sibling := range ctx.Siblings() {
+				if sibling.Name == "if" && testedVariable(sibling) == variable {
+					tests = append(tests, sibling)
+				}
+			}
+			switch {
+			case len(tests) > 1 && tests[0] == d:
+				return []Diagnostic{r.report(d, "%d if blocks test %s; a map from %s can replace them", len(tests), variable, variable)}
+			case len(tests) == 1 && onlySets(ctx.Tree, d):
+				return []Diagnostic{r.report(d, "if only sets variables based on %s; define them with a map instead", variable)}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,79 @@
+package lint
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIfRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		rules []string
+		want  []string
+	}{
+		{
+			name:  "safe directives in if",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    location / {\n      if ($request_method = POST) {\n        return 405;\n      }\n      if ($uri ~ ^/old) {\n        rewrite ^/old(.*)$ /new$1 last;\n      }\n    }\n  }\n}\n"},
+			rules: []string{"if-in-location"},
+		},
+		{
+			name:  "unsafe directives in if",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    location / {\n      if ($slow) {\n        limit_rate 10k;\n        rewrite ^ /slow break;\n      }\n    }\n  }\n}\n"},
+			rules: []string{"if-in-location"},
+			want:  []string{"nginx.conf:5 if-in-location", "nginx.conf:6 if-in-location"},
+		},
+		{
+			name:  "if in server is not checked",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    if ($host = old.example.com) {\n      set $redirect 1;\n    }\n  }\n}\n"},
+			rules: []string{"if-in-location"},
+		},
+		{
+			name:  "nested if",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    location / {\n      if ($a) {\n        if ($b) {\n          return 403;\n        }\n      }\n    }\n  }\n}\n"},
+			rules: []string{"nested-if"},
+			want:  []string{"nginx.conf:5 nested-if"},
+		},
+		{
+			name:  "ifs testing the same variable",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    if ($host = a.example.com) {\n      return 301 https://a.example.org;\n    }\n    if ($host ~* ^b\\.) {\n      return 301 https://b.example.org;\n    }\n    if ($scheme = http) {\n      return 301 https://$host$request_uri;\n    }\n  }\n}\n"},
+			rules: []string{"if-to-map"},
+			want:  []string{"nginx.conf:3 if-to-map"},
+		},
+		{
+			name:  "if that only sets a variable",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    location / {\n      if ($http_user_agent ~* bot) {\n        set $is_bot 1;\n      }\n      if (-f $request_filename) {\n        set $exists 1;\n      }\n    }\n  }\n}\n"},
+			rules: []string{"if-to-map"},
+			want:  []string{"nginx.conf:4 if-to-map"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintFiles(t, tt.files, tt.rules...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIfRulesLinkExplanation(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf": "http {\n  server {\n    location / {\n      if ($slow) {\n        limit_rate 10k;\n      }\n    }\n  }\n}\n",
+	})
+	l, err := New([]string{"if-in-location"}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	diagnostics := l.Lint(Load("", filepath.Join(root, "nginx.conf")))
+	if len(diagnostics) != 1 {
+		t.Fatalf("Lint() = %v, want one finding", diagnostics)
+	}
+	if d := diagnostics[0]; d.URL != ifIsEvilURL || !strings.Contains(d.String(), "(see "+ifIsEvilURL+")") {
+		t.Errorf("Lint() = %v, want a link to %s", d, ifIsEvilURL)
+	}
+}
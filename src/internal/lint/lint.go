@@ -0,0 +1,351 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// Severity is how serious a finding is
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return "unknown"
+}
+
+// ParseSeverity parses the name of a severity as printed by String
+func ParseSeverity(s string) (Severity, error) {
+	for _, severity := range []Severity{Info, Warning, Error} {
+		if strings.EqualFold(s, severity.String()) {
+			return severity, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid severity %q, must be info, warning or error", s)
+}
+
+// Diagnostic is a single finding reported by a rule. Suggestion, when
+// set, is what to write instead, and URL points to an explanation.
+type Diagnostic struct {
+	Rule       string
+	Severity   Severity
+	File       string
+	Line       int
+	Message    string
+	Suggestion string
+	URL        string
+}
+
+func (d Diagnostic) String() string {
+	msg := d.Message
+	if d.Suggestion != "" {
+		msg += "; use \"" + d.Suggestion + "\" instead"
+	}
+	if d.URL != "" {
+		msg += " (see " + d.URL + ")"
+	}
+	if d.File == "" {
+		return fmt.Sprintf("%s: %s [%s]", d.Severity, msg, d.Rule)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s [%s]", d.File, d.Line, d.Severity, msg, d.Rule)
+}
+
+// Rule checks directives in the contexts it applies to
+type Rule interface {
+	// ID is the name used to enable, disable and report the rule
+	ID() string
+	Description() string
+	Severity() Severity
+	// Contexts lists the contexts, as named by Context.Name, of the
+	// directives the rule wants to see. Nil means every context.
+	Contexts() []string
+	Check(d *nginx.Directive, ctx *Context) []Diagnostic
+}
+
+// TreeRule is implemented by rules that look at the configuration as a
+// whole rather than one directive at a time
+type TreeRule interface {
+	Rule
+	CheckTree(t *Tree) []Diagnostic
+}
+
+// Context describes where a directive appears
+type Context struct {
+	// Name is "main" at the top level, otherwise the name of the enclosing
+	// block such as "http" or "location". Blocks of the stream and mail
+	// modules are prefixed with the module, as in "stream server", and if
+	// blocks with their own context, as in "if in location".
+	Name    string
+	Parents []*nginx.Directive
+	Tree    *Tree
+
+	siblings []*nginx.Directive
+}
+
+// Parent returns the innermost enclosing block, or nil at the top level
+func (c *Context) Parent() *nginx.Directive {
+	if len(c.Parents) == 0 {
+		return nil
+	}
+	return c.Parents[len(c.Parents)-1]
+}
+
+// Enclosing returns the innermost enclosing block called name, or nil
+func (c *Context) Enclosing(name string) *nginx.Directive {
+	for i := len(c.Parents) - 1; i >= 0; i-- {
+		if c.Parents[i].Name == name {
+			return c.Parents[i]
+		}
+	}
+	return nil
+}
+
+// Siblings returns the directives in the same block, the directive itself
+// included, with includes expanded
+func (c *Context) Siblings() []*nginx.Directive {
+	return c.siblings
+}
+
+// contextName names the context a directive nested in parents appears in
+func contextName(parents []*nginx.Directive) string {
+	if len(parents) == 0 {
+		return "main"
+	}
+
+	last := parents[len(parents)-1]
+	if last.Name == "if" {
+		return "if in " + contextName(parents[:len(parents)-1])
+	}
+
+	for _, p := range parents[:len(parents)-1] {
+		if p.Name == "stream" || p.Name == "mail" {
+			return p.Name + " " + last.Name
+		}
+	}
+	return last.Name
+}
+
+// Tree is the configuration reachable from an entry point
+type Tree struct {
+	Graph  *nginx.Graph
+	Entry  string
+	Schema *Schema
+	// AutoindexAllow lists the location prefixes where directory listings
+	// are intended
+	AutoindexAllow []string
+	// Target is the nginx version the configuration is meant for. Nil
+	// means the newest.
+	Target Version
+}
+
+// Load parses entry and every file it includes. Relative include paths are
+// resolved against prefix, or the directory of entry when prefix is empty.
+func Load(prefix, entry string) *Tree {
+	if prefix == "" {
+		prefix = filepath.Dir(entry)
+	}
+	entry = filepath.Clean(entry)
+	return &Tree{Graph: nginx.LoadGraph(prefix, entry), Entry: entry, Schema: DefaultSchema()}
+}
+
+// Reload loads the tree again, reading the files in overlay from there
+// instead of from disk
+func (t *Tree) Reload(overlay map[string][]byte) *Tree {
+	reloaded := *t
+	reloaded.Graph = nginx.LoadGraphOverlay(t.Graph.Prefix, overlay, t.Entry)
+	return &reloaded
+}
+
+// Directives returns the top level directives of the entry point with
+// includes expanded
+func (t *Tree) Directives() []*nginx.Directive {
+	return t.Graph.Expand(t.Graph.Configs[t.Entry])
+}
+
+// Children returns the directives in d's block with includes expanded
+func (t *Tree) Children(d *nginx.Directive) []*nginx.Directive {
+	return t.Graph.Expand(d.Block)
+}
+
+// Walk calls fn for every directive nginx would see, in order, following
+// includes at every level. Comments and the contents of freeform blocks
+// such as map are skipped.
+func (t *Tree) Walk(fn func(d *nginx.Directive, ctx *Context)) {
+	t.walk(t.Directives(), nil, func(d *nginx.Directive, parents, siblings []*nginx.Directive) {
+		if !d.IsComment() {
+			fn(d, &Context{Name: contextName(parents), Parents: parents, Tree: t, siblings: siblings})
+		}
+	})
+}
+
+// walk visits directives with their parents and the expanded block they
+// appear in. A block is not entered again below itself, which can only
+// happen through an include cycle.
+func (t *Tree) walk(directives, parents []*nginx.Directive, fn func(d *nginx.Directive, parents, siblings []*nginx.Directive)) {
+	for _, d := range directives {
+		fn(d, parents, directives)
+		if !d.IsBlock() || contains(parents, d) || (t.Schema != nil && t.Schema.Freeform(d.Name)) {
+			continue
+		}
+		t.walk(t.Children(d), append(parents[:len(parents):len(parents)], d), fn)
+	}
+}
+
+func contains(directives []*nginx.Directive, d *nginx.Directive) bool {
+	for _, n := range directives {
+		if n == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Linter runs a set of rules over a Tree
+type Linter struct {
+	Rules []Rule
+	// Formatter lays out the directives Fix rewrites. Nil keeps the
+	// indentation each file already uses.
+	Formatter *nginx.Formatter
+}
+
+// New returns a Linter running every registered rule, or only those in
+// enable when it is not empty, minus those in disable. Both lists may name
+// rule sets as well as rules.
+func New(enable, disable []string) (*Linter, error) {
+	enable, err := expandSets(enable)
+	if err != nil {
+		return nil, err
+	}
+	if disable, err = expandSets(disable); err != nil {
+		return nil, err
+	}
+
+	selected := Rules()
+	if len(enable) > 0 {
+		selected = nil
+		for _, id := range enable {
+			selected = append(selected, Lookup(id))
+		}
+	}
+
+	l := &Linter{}
+	for _, r := range selected {
+		if !containsString(disable, r.ID()) && !containsRule(l.Rules, r) {
+			l.Rules = append(l.Rules, r)
+		}
+	}
+	return l, nil
+}
+
+// expandSets replaces the rule sets in ids with their rules
+func expandSets(ids []string) ([]string, error) {
+	var expanded []string
+	for _, id := range ids {
+		switch {
+		case Lookup(id) != nil:
+			expanded = append(expanded, id)
+		case Set(id) != nil:
+			expanded = append(expanded, Set(id)...)
+		default:
+			return nil, fmt.Errorf("unknown rule %q", id)
+		}
+	}
+	return expanded, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsRule(rules []Rule, r Rule) bool {
+	for _, v := range rules {
+		if v.ID() == r.ID() {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint runs the rules over t and returns their findings sorted by
+// position. Files that could not be loaded are reported as errors of the
+// "syntax" rule, which cannot be disabled. Findings on directives covered
+// by a disable comment are dropped.
+func (l *Linter) Lint(t *Tree) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, err := range t.Graph.Errors {
+		diagnostics = append(diagnostics, loadError(err))
+	}
+
+	suppressed := suppressions(t)
+
+	var found []Diagnostic
+	t.Walk(func(d *nginx.Directive, ctx *Context) {
+		for _, r := range l.Rules {
+			if appliesTo(r, ctx.Name) {
+				found = append(found, r.Check(d, ctx)...)
+			}
+		}
+	})
+	for _, r := range l.Rules {
+		if tr, ok := r.(TreeRule); ok {
+			found = append(found, tr.CheckTree(t)...)
+		}
+	}
+
+	seen := make(map[Diagnostic]bool)
+	for _, diag := range found {
+		if seen[diag] || suppressed.covers(diag) {
+			continue
+		}
+		seen[diag] = true
+		diagnostics = append(diagnostics, diag)
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Rule < b.Rule
+	})
+	return diagnostics
+}
+
+func appliesTo(r Rule, context string) bool {
+	contexts := r.Contexts()
+	return contexts == nil || containsString(contexts, context)
+}
+
+func loadError(err error) Diagnostic {
+	diag := Diagnostic{Rule: "syntax", Severity: Error, Message: err.Error()}
+	var parseErr *nginx.ParseError
+	if errors.As(err, &parseErr) {
+		diag.File, diag.Line, diag.Message = parseErr.File, parseErr.Line, parseErr.Message
+	}
+	return diag
+}
@@ -0,0 +1,345 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// writeTree creates files under a temporary directory and returns its path
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create test file %s: %v", name, err)
+		}
+	}
+	return root
+}
+
+// lintFiles lints nginx.conf in a tree made of files with only the given
+// rules enabled and returns the findings as "file:line rule", with paths
+// relative to the tree
+func lintFiles(t *testing.T, files map[string]string, rules ...string) []string {
+	t.Helper()
+	root := writeTree(t, files)
+
+	l, err := New(rules, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var found []string
+	for _, d := range l.Lint(Load("", filepath.Join(root, "nginx.conf"))) {
+		rel, err := filepath.Rel(root, d.File)
+		if err != nil || d.File == "" {
+			rel = d.File
+		}
+		found = append(found, rel+":"+strconv.Itoa(d.Line)+" "+d.Rule)
+	}
+	return found
+}
+
+func TestContextName(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf": "events {}\nhttp {\n  server {\n    location / {\n      if ($x) {\n        return 404;\n      }\n    }\n  }\n}\nstream {\n  server {\n    listen 53;\n  }\n}\n",
+	})
+
+	got := make(map[string]string)
+	Load("", filepath.Join(root, "nginx.conf")).Walk(func(d *nginx.Directive, ctx *Context) {
+		got[d.Name+"@"+strconv.Itoa(d.Line)] = ctx.Name
+	})
+
+	want := map[string]string{
+		"events@1":   "main",
+		"http@2":     "main",
+		"server@3":   "http",
+		"location@4": "server",
+		"if@5":       "location",
+		"return@6":   "if in location",
+		"stream@11":  "main",
+		"server@12":  "stream",
+		"listen@13":  "stream server",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk() contexts = %v, want %v", got, want)
+	}
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		rules []string
+		want  []string
+	}{
+		{
+			name: "finding in an included file",
+			files: map[string]string{
+				"nginx.conf": "http {\n  server {\n    include loc.conf;\n  }\n}\n",
+				"loc.conf":   "location / {\n  server_tokens on;\n}\n",
+			},
+			rules: []string{"server-tokens"},
+			want:  []string{"loc.conf:2 server-tokens"},
+		},
+		{
+			name: "disabled on the same line",
+			files: map[string]string{
+				"nginx.conf": "http {\n  server {\n    location / {\n      server_tokens on; # gofmtnginx:disable=server-tokens\n    }\n  }\n}\n",
+			},
+			rules: []string{"server-tokens"},
+		},
+		{
+			name: "disabled on the line above",
+			files: map[string]string{
+				"nginx.conf": "http {\n  server {\n    location / {\n      # gofmtnginx:disable=server-tokens\n      server_tokens on;\n    }\n  }\n}\n",
+			},
+			rules: []string{"server-tokens"},
+		},
+		{
+			name: "disabled for a block",
+			files: map[string]string{
+				"nginx.conf": "http {\n  # gofmtnginx:disable=all\n  server {\n    location / {\n      server_tokens on;\n    }\n  }\n}\n",
+			},
+			rules: []string{"server-tokens"},
+		},
+		{
+			name: "disabled on the opening line of a block",
+			files: map[string]string{
+				"nginx.conf": "http {\n  server { # gofmtnginx:disable=server-tokens\n    listen 80;\n    server_tokens on;\n  }\n}\n",
+			},
+			rules: []string{"server-tokens"},
+		},
+		{
+			name: "comment separated by a blank line does not apply",
+			files: map[string]string{
+				"nginx.conf": "http {\n  server {\n    location / {\n      # gofmtnginx:disable=server-tokens\n\n      server_tokens on;\n    }\n  }\n}\n",
+			},
+			rules: []string{"server-tokens"},
+			want:  []string{"nginx.conf:6 server-tokens"},
+		},
+		{
+			name: "comment on the opening line of a block stays in the block",
+			files: map[string]string{
+				"nginx.conf": "http {\n  server {\n    location / { # gofmtnginx:disable=server-tokens\n    }\n    server_tokens on;\n  }\n}\n",
+			},
+			rules: []string{"server-tokens"},
+			want:  []string{"nginx.conf:5 server-tokens"},
+		},
+		{
+			name: "trailing comment of the previous directive does not apply",
+			files: map[string]string{
+				"nginx.conf": "http {\n  server {\n    location / {\n      index a; # gofmtnginx:disable=server-tokens\n      server_tokens on;\n    }\n  }\n}\n",
+			},
+			rules: []string{"server-tokens"},
+			want:  []string{"nginx.conf:5 server-tokens"},
+		},
+		{
+			name: "other rules stay enabled",
+			files: map[string]string{
+				"nginx.conf": "http {\n  server {\n    location / {\n      server_tokens on; # gofmtnginx:disable=include\n    }\n  }\n}\n",
+			},
+			rules: []string{"server-tokens"},
+			want:  []string{"nginx.conf:4 server-tokens"},
+		},
+		{
+			name: "directives in the wrong context",
+			files: map[string]string{
+				"nginx.conf": "http {\n  proxy_pass http://a;\n  server {\n    location / {\n      listen 80;\n    }\n  }\n}\n",
+			},
+			rules: []string{"context"},
+			want:  []string{"nginx.conf:2 context", "nginx.conf:5 context"},
+		},
+		{
+			name: "directives with several forms",
+			files: map[string]string{
+				"nginx.conf": "http {\n  upstream a {\n    server 127.0.0.1;\n  }\n  server {\n    listen 80;\n  }\n}\nstream {\n  server {\n    listen 53 udp;\n    proxy_pass a;\n  }\n}\n",
+			},
+			rules: []string{"context", "arity", "duplicate", "unknown-directive"},
+		},
+		{
+			name: "wrong arity",
+			files: map[string]string{
+				"nginx.conf": "http {\n  sendfile maybe;\n  root;\n  server {\n    listen 80 {\n    }\n  }\n}\n",
+			},
+			rules: []string{"arity"},
+			want:  []string{"nginx.conf:2 arity", "nginx.conf:3 arity", "nginx.conf:5 arity"},
+		},
+		{
+			name: "duplicates",
+			files: map[string]string{
+				"nginx.conf": "http {\n  root /a;\n  include more.conf;\n  add_header A b;\n  add_header C d;\n  add_header C d;\n  gzip on;\n  gzip on;\n}\n",
+				"more.conf":  "root /b;\n",
+			},
+			rules: []string{"duplicate", "exact-duplicate"},
+			want:  []string{"more.conf:1 duplicate", "nginx.conf:6 exact-duplicate", "nginx.conf:8 duplicate"},
+		},
+		{
+			name: "directives nginx lets repeat",
+			files: map[string]string{
+				"nginx.conf": "http {\n  server {\n    location /a {\n      return 200 a;\n      return 404;\n    }\n    location /b {\n      rewrite ^ /c break;\n      break;\n      break;\n    }\n  }\n  upstream u {\n    least_conn;\n    least_conn;\n    server a;\n  }\n  ssl_protocols TLSv1.2;\n  ssl_protocols TLSv1.3;\n}\n",
+			},
+			rules: []string{"duplicate"},
+		},
+		{
+			name: "unknown directives",
+			files: map[string]string{
+				"nginx.conf": "http {\n  more_set_headers \"X: y\";\n  map $a $b {\n    default 1;\n    hostnames;\n  }\n}\n",
+			},
+			rules: []string{"unknown-directive", "context"},
+			want:  []string{"nginx.conf:2 unknown-directive"},
+		},
+		{
+			name: "missing include",
+			files: map[string]string{
+				"nginx.conf": "http {\n  include missing.conf;\n}\n",
+			},
+			rules: []string{"include"},
+			want:  []string{"nginx.conf:2 include"},
+		},
+		{
+			name: "syntax errors are always reported",
+			files: map[string]string{
+				"nginx.conf": "http {\n  server_name \"a;\n}\n",
+			},
+			rules: []string{"include"},
+			want:  []string{"nginx.conf:2 syntax"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintFiles(t, tt.files, tt.rules...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New([]string{"no-such-rule"}, nil); err == nil {
+		t.Errorf("New() with an unknown rule did not return an error")
+	}
+
+	l, err := New(nil, []string{"include"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for _, r := range l.Rules {
+		if r.ID() == "include" {
+			t.Errorf("New() kept disabled rule %q", r.ID())
+		}
+	}
+	if len(l.Rules) != len(Rules())-1 {
+		t.Errorf("New() selected %d rules, want %d", len(l.Rules), len(Rules())-1)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for _, want := range []Severity{Info, Warning, Error} {
+		if got, err := ParseSeverity(want.String()); err != nil || got != want {
+			t.Errorf("ParseSeverity(%q) = %v, %v, want %v", want.String(), got, err, want)
+		}
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Errorf("ParseSeverity(%q) did not return an error", "fatal")
+	}
+}
+
+// stockConfig is the nginx.conf shipped with nginx, with the commented
+// examples enabled and the paths distribution packages set
+const stockConfig = `user  nginx;
+worker_processes  auto;
+
+error_log  /var/log/nginx/error.log notice;
+pid        /run/nginx.pid;
+
+events {
+    worker_connections  1024;
+}
+
+http {
+    include       mime.types;
+    default_type  application/octet-stream;
+
+    log_format  main  '$remote_addr - $remote_user [$time_local] "$request" '
+                      '$status $body_bytes_sent "$http_referer" '
+                      '"$http_user_agent" "$http_x_forwarded_for"';
+
+    access_log  /var/log/nginx/access.log  main;
+
+    client_body_temp_path /var/cache/nginx/client_temp;
+    proxy_temp_path       /var/cache/nginx/proxy_temp;
+    fastcgi_temp_path     /var/cache/nginx/fastcgi_temp;
+    uwsgi_temp_path       /var/cache/nginx/uwsgi_temp;
+    scgi_temp_path        /var/cache/nginx/scgi_temp;
+
+    sendfile        on;
+    tcp_nopush      on;
+
+    keepalive_timeout  65;
+
+    gzip  on;
+
+    server {
+        listen       80;
+        server_name  localhost;
+
+        location / {
+            root   html;
+            index  index.html index.htm;
+        }
+
+        error_page  404              /404.html;
+
+        error_page   500 502 503 504  /50x.html;
+        location = /50x.html {
+            root   html;
+        }
+
+        location ~ \.php$ {
+            root           html;
+            fastcgi_pass   127.0.0.1:9000;
+            fastcgi_index  index.php;
+            fastcgi_param  SCRIPT_FILENAME  /scripts$fastcgi_script_name;
+            include        fastcgi_params;
+        }
+
+        location ~ /\.ht {
+            deny  all;
+        }
+    }
+
+    server {
+        listen       8000;
+        listen       somename:8080;
+        server_name  somename  alias  another.alias;
+
+        location / {
+            root   html;
+            index  index.html index.htm;
+        }
+    }
+}
+`
+
+func TestStockConfig(t *testing.T) {
+	files := map[string]string{
+		"nginx.conf":     stockConfig,
+		"mime.types":     "types {\n    text/html  html htm shtml;\n    text/css   css;\n    image/gif  gif;\n}\n",
+		"fastcgi_params": "fastcgi_param  QUERY_STRING       $query_string;\nfastcgi_param  REQUEST_METHOD     $request_method;\nfastcgi_param  CONTENT_TYPE       $content_type;\nfastcgi_param  SCRIPT_NAME        $fastcgi_script_name;\nfastcgi_param  REQUEST_URI        $request_uri;\nfastcgi_param  DOCUMENT_ROOT      $document_root;\nfastcgi_param  SERVER_PROTOCOL    $server_protocol;\nfastcgi_param  REQUEST_SCHEME     $scheme;\nfastcgi_param  HTTPS              $https if_not_empty;\nfastcgi_param  REMOTE_ADDR        $remote_addr;\nfastcgi_param  SERVER_NAME        $server_name;\nfastcgi_param  REDIRECT_STATUS    200;\n",
+	}
+	if got := lintFiles(t, files); len(got) > 0 {
+		t.Errorf("Lint() = %v, want no findings", got)
+	}
+}
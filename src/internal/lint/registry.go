@@ -0,0 +1,83 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Rule)
+	sets       = make(map[string][]string)
+)
+
+// Register makes a rule available to New. It panics if a rule with the
+// same ID is already registered.
+func Register(r Rule) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[r.ID()]; ok {
+		panic(fmt.Sprintf("lint: rule %q registered twice", r.ID()))
+	}
+	if _, ok := sets[r.ID()]; ok {
+		panic(fmt.Sprintf("lint: rule %q has the name of a rule set", r.ID()))
+	}
+	registry[r.ID()] = r
+}
+
+// RegisterSet names a group of rules so they can be enabled or disabled
+// together. It panics if the name is already taken by a rule or set.
+func RegisterSet(name string, ids ...string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("lint: rule set %q has the name of a rule", name))
+	}
+	if _, ok := sets[name]; ok {
+		panic(fmt.Sprintf("lint: rule set %q registered twice", name))
+	}
+	sets[name] = ids
+}
+
+// Set returns the IDs of the rules in a rule set, or nil
+func Set(name string) []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return sets[name]
+}
+
+// Sets returns the names of every registered rule set, sorted
+func Sets() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(sets))
+	for name := range sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the registered rule with the given ID, or nil
+func Lookup(id string) Rule {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry[id]
+}
+
+// Rules returns every registered rule sorted by ID
+func Rules() []Rule {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	rules := make([]Rule, 0, len(registry))
+	for _, r := range registry {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID() < rules[j].ID() })
+	return rules
+}
@@ -0,0 +1,94 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// rule implements Rule, TreeRule, Fixer and TreeFixer for the built-in
+// rules with plain functions, any of which may be nil
+type rule struct {
+	id          string
+	description string
+	severity    Severity
+	contexts    []string
+	url         string
+	check       func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic
+	tree        func(r *rule, t *Tree) []Diagnostic
+	fix         func(r *rule, d *nginx.Directive, ctx *Context, e *Editor) error
+	fixTree     func(r *rule, t *Tree, e *Editor) error
+}
+
+func (r *rule) ID() string          { return r.id }
+func (r *rule) Description() string { return r.description }
+func (r *rule) Severity() Severity  { return r.severity }
+func (r *rule) Contexts() []string  { return r.contexts }
+
+func (r *rule) Check(d *nginx.Directive, ctx *Context) []Diagnostic {
+	if r.check == nil {
+		return nil
+	}
+	return r.check(r, d, ctx)
+}
+
+func (r *rule) CheckTree(t *Tree) []Diagnostic {
+	if r.tree == nil {
+		return nil
+	}
+	return r.tree(r, t)
+}
+
+func (r *rule) Fix(d *nginx.Directive, ctx *Context, e *Editor) error {
+	if r.fix == nil {
+		return nil
+	}
+	return r.fix(r, d, ctx, e)
+}
+
+func (r *rule) FixTree(t *Tree, e *Editor) error {
+	if r.fixTree == nil {
+		return nil
+	}
+	return r.fixTree(r, t, e)
+}
+
+// report returns a finding of r positioned at d
+func (r *rule) report(d *nginx.Directive, format string, args ...any) Diagnostic {
+	return r.reportAt(d.File, d.Line, format, args...)
+}
+
+func (r *rule) reportAt(file string, line int, format string, args ...any) Diagnostic {
+	return Diagnostic{
+		Rule:     r.id,
+		Severity: r.severity,
+		File:     file,
+		Line:     line,
+		Message:  fmt.Sprintf(format, args...),
+		URL:      r.url,
+	}
+}
+
+func init() {
+	Register(&rule{
+		id:          "include",
+		description: "include directives that form a cycle or match no files",
+		severity:    Warning,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			var diagnostics []Diagnostic
+			for _, d := range t.Graph.Diagnostics("") {
+				msg := fmt.Sprintf("%s: %s", d.Kind, d.Target)
+				if len(d.Chain) > 0 {
+					links := make([]string, 0, len(d.Chain))
+					for _, inc := range d.Chain {
+						links = append(links, fmt.Sprintf("%s:%d", inc.File, inc.Line))
+					}
+					msg += " (included via " + strings.Join(links, " -> ") + ")"
+				}
+				diagnostics = append(diagnostics, r.reportAt(d.Include.File, d.Include.Line, "%s", msg))
+			}
+			return diagnostics
+		},
+	})
+}
@@ -0,0 +1,243 @@
+package lint
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed schema.json
+var schemaJSON []byte
+
+// Schema describes the directives nginx understands, those that have
+// been deprecated or removed over time, and the variables nginx and its
+// modules define. A variable ending in "*", such as "http_*", stands for
+// every variable with that prefix.
+type Schema struct {
+	Directives   map[string]Variants `json:"directives"`
+	Deprecations []*Deprecation      `json:"deprecations"`
+	Variables    []string            `json:"variables"`
+}
+
+// Spec describes one form of a directive. Args is "flag" for on/off
+// directives, a count such as "1", a range such as "1-3", or a minimum
+// such as "2+". Freeform blocks, like map and types, hold data rather than
+// directives.
+type Spec struct {
+	Contexts []string `json:"contexts"`
+	Args     string   `json:"args"`
+	Block    bool     `json:"block,omitempty"`
+	Multiple bool     `json:"multiple,omitempty"`
+	Freeform bool     `json:"freeform,omitempty"`
+
+	min, max int
+	flag     bool
+}
+
+// Variants lists the forms of a directive that differ by context, such as
+// server in http and server in upstream. A single form may be written in
+// JSON as an object rather than a list.
+type Variants []*Spec
+
+func (v *Variants) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		var spec Spec
+		if err := json.Unmarshal(data, &spec); err != nil {
+			return err
+		}
+		*v = Variants{&spec}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]*Spec)(v))
+}
+
+var (
+	defaultSchema     *Schema
+	defaultSchemaOnce sync.Once
+)
+
+// DefaultSchema returns the built-in schema of core and common module
+// directives
+func DefaultSchema() *Schema {
+	defaultSchemaOnce.Do(func() {
+		s, err := ParseSchema(schemaJSON)
+		if err != nil {
+			panic(fmt.Sprintf("lint: invalid built-in schema: %v", err))
+		}
+		defaultSchema = s
+	})
+	return defaultSchema
+}
+
+// ParseSchema reads a schema from JSON in the format of the built-in one
+func ParseSchema(data []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+
+	for name, variants := range s.Directives {
+		if len(variants) == 0 {
+			return nil, fmt.Errorf("directive %q has no definition", name)
+		}
+		for _, spec := range variants {
+			if err := spec.parseArgs(); err != nil {
+				return nil, fmt.Errorf("directive %q: %w", name, err)
+			}
+			if len(spec.Contexts) == 0 {
+				return nil, fmt.Errorf("directive %q has no contexts", name)
+			}
+		}
+	}
+	for _, dep := range s.Deprecations {
+		if err := dep.parse(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// LoadSchema reads a schema file
+func LoadSchema(fileName string) (*Schema, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening schema file: %w", err)
+	}
+	s, err := ParseSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return s, nil
+}
+
+// Extend returns a schema holding the directives and deprecations of s
+// and other. A directive defined in both takes its definition from other.
+func (s *Schema) Extend(other *Schema) *Schema {
+	merged := &Schema{Directives: make(map[string]Variants, len(s.Directives)+len(other.Directives))}
+	for name, variants := range s.Directives {
+		merged.Directives[name] = variants
+	}
+	for name, variants := range other.Directives {
+		merged.Directives[name] = variants
+	}
+	merged.Deprecations = append(append([]*Deprecation{}, s.Deprecations...), other.Deprecations...)
+	merged.Variables = append(append([]string{}, s.Variables...), other.Variables...)
+	return merged
+}
+
+// Known reports whether the schema defines a directive, or knows it as
+// one that has been removed
+func (s *Schema) Known(name string) bool {
+	if len(s.Directives[name]) > 0 {
+		return true
+	}
+	for _, dep := range s.Deprecations {
+		if dep.Directive == name && dep.Parameter == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Builtin reports whether a variable, named without "$", is defined by
+// nginx or a module rather than by the configuration
+func (s *Schema) Builtin(name string) bool {
+	name = strings.ToLower(name)
+	for _, v := range s.Variables {
+		if prefix, ok := strings.CutSuffix(v, "*"); ok && strings.HasPrefix(name, prefix) || v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Spec returns the form of a directive allowed in context, or nil
+func (s *Schema) Spec(name, context string) *Spec {
+	for _, spec := range s.Directives[name] {
+		if spec.Allows(context) {
+			return spec
+		}
+	}
+	return nil
+}
+
+// Contexts returns every context a directive is allowed in
+func (s *Schema) Contexts(name string) []string {
+	var contexts []string
+	for _, spec := range s.Directives[name] {
+		for _, c := range spec.Contexts {
+			if !containsString(contexts, c) {
+				contexts = append(contexts, c)
+			}
+		}
+	}
+	return contexts
+}
+
+// Freeform reports whether the block of a directive holds data rather
+// than directives
+func (s *Schema) Freeform(name string) bool {
+	for _, spec := range s.Directives[name] {
+		if spec.Freeform {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether the form is allowed in context. "*" allows any.
+func (spec *Spec) Allows(context string) bool {
+	return containsString(spec.Contexts, "*") || containsString(spec.Contexts, context)
+}
+
+func (spec *Spec) parseArgs() error {
+	args := spec.Args
+	switch {
+	case args == "flag":
+		spec.min, spec.max, spec.flag = 1, 1, true
+		return nil
+	case strings.HasSuffix(args, "+"):
+		n, err := strconv.Atoi(strings.TrimSuffix(args, "+"))
+		spec.min, spec.max = n, -1
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid args %q", args)
+		}
+		return nil
+	case strings.Contains(args, "-"):
+		low, high, _ := strings.Cut(args, "-")
+		var err1, err2 error
+		spec.min, err1 = strconv.Atoi(low)
+		spec.max, err2 = strconv.Atoi(high)
+		if err1 != nil || err2 != nil || spec.min < 0 || spec.max < spec.min {
+			return fmt.Errorf("invalid args %q", args)
+		}
+		return nil
+	}
+
+	n, err := strconv.Atoi(args)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid args %q", args)
+	}
+	spec.min, spec.max = n, n
+	return nil
+}
+
+// checkArgs returns why args do not fit the form, or "" if they do
+func (spec *Spec) checkArgs(args []string) string {
+	n := len(args)
+	switch {
+	case spec.flag && n == 1 && !strings.EqualFold(args[0], "on") && !strings.EqualFold(args[0], "off"):
+		return fmt.Sprintf("takes \"on\" or \"off\", not %q", args[0])
+	case n >= spec.min && (spec.max < 0 || n <= spec.max):
+		return ""
+	case spec.max < 0:
+		return fmt.Sprintf("takes at least %d argument(s), got %d", spec.min, n)
+	case spec.min == spec.max:
+		return fmt.Sprintf("takes %d argument(s), got %d", spec.min, n)
+	}
+	return fmt.Sprintf("takes %d to %d arguments, got %d", spec.min, spec.max, n)
+}
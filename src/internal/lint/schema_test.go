@@ -0,0 +1,140 @@
+package lint
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{
+			name:  "single form",
+			input: `{"directives": {"echo": {"contexts": ["location"], "args": "1+"}}}`,
+		},
+		{
+			name:  "several forms",
+			input: `{"directives": {"server": [{"contexts": ["http"], "args": "0", "block": true}, {"contexts": ["upstream"], "args": "1+"}]}}`,
+		},
+		{
+			name:    "invalid args",
+			input:   `{"directives": {"echo": {"contexts": ["location"], "args": "some"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "inverted range",
+			input:   `{"directives": {"echo": {"contexts": ["location"], "args": "3-1"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "no contexts",
+			input:   `{"directives": {"echo": {"args": "1"}}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSchema([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchemaSpec(t *testing.T) {
+	s := DefaultSchema()
+
+	if spec := s.Spec("server", "upstream"); spec == nil || spec.Block {
+		t.Errorf("Spec(server, upstream) = %+v, want the upstream form", spec)
+	}
+	if spec := s.Spec("server", "http"); spec == nil || !spec.Block {
+		t.Errorf("Spec(server, http) = %+v, want the block form", spec)
+	}
+	if spec := s.Spec("proxy_pass", "http"); spec != nil {
+		t.Errorf("Spec(proxy_pass, http) = %+v, want nil", spec)
+	}
+	if spec := s.Spec("include", "if in location"); spec == nil {
+		t.Errorf("Spec(include, if in location) = nil, want any context")
+	}
+}
+
+func TestCheckArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		in   []string
+		ok   bool
+	}{
+		{"flag", []string{"on"}, true},
+		{"flag", []string{"OFF"}, true},
+		{"flag", []string{"yes"}, false},
+		{"flag", nil, false},
+		{"1", []string{"a"}, true},
+		{"1", []string{"a", "b"}, false},
+		{"1-2", []string{"a", "b"}, true},
+		{"1-2", nil, false},
+		{"2+", []string{"a", "b", "c"}, true},
+		{"2+", []string{"a"}, false},
+	}
+
+	for _, tt := range tests {
+		spec := &Spec{Args: tt.args}
+		if err := spec.parseArgs(); err != nil {
+			t.Fatalf("parseArgs(%q) error = %v", tt.args, err)
+		}
+		if got := spec.checkArgs(tt.in) == ""; got != tt.ok {
+			t.Errorf("checkArgs(%q, %v) ok = %v, want %v", tt.args, tt.in, got, tt.ok)
+		}
+	}
+}
+
+func TestSchemaExtend(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf": "http {\n  server {\n    more_set_headers \"X-A: b\";\n    location / {\n      echo;\n    }\n  }\n}\n",
+	})
+	extra, err := ParseSchema([]byte(`{"directives": {
+		"more_set_headers": {"contexts": ["http", "server", "location"], "args": "1+", "multiple": true},
+		"echo": {"contexts": ["location"], "args": "1+"}
+	}}`))
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	tree := Load("", filepath.Join(root, "nginx.conf"))
+	tree.Schema = tree.Schema.Extend(extra)
+
+	l, err := New([]string{"unknown-directive", "arity"}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var got []string
+	for _, d := range l.Lint(tree) {
+		got = append(got, d.Rule)
+	}
+	if want := []string{"arity"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() rules = %v, want %v", got, want)
+	}
+	if DefaultSchema().Known("echo") {
+		t.Errorf("Extend() modified the default schema")
+	}
+}
+
+func TestSchemaBuiltin(t *testing.T) {
+	s := DefaultSchema()
+	for _, name := range []string{"uri", "Request_URI", "http_x_forwarded_for", "upstream_http_location"} {
+		if !s.Builtin(name) {
+			t.Errorf("Builtin(%q) = false, want true", name)
+		}
+	}
+	for _, name := range []string{"reqeust_uri", "http", "backend"} {
+		if s.Builtin(name) {
+			t.Errorf("Builtin(%q) = true, want false", name)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package lint
+
+import (
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// weakCiphers are the parts of OpenSSL cipher names and aliases that mark
+// ciphers without real protection: no encryption, no authentication,
+// export grade keys, or broken algorithms
+var weakCiphers = []string{"NULL", "EXP", "ADH", "AECDH", "RC4", "DES", "MD5"}
+
+// insecureProtocols are the ssl_protocols values with known weaknesses
+var insecureProtocols = []string{"SSLv2", "SSLv3", "TLSv1", "TLSv1.1"}
+
+// weakCipher returns the first cipher in an OpenSSL cipher list that is
+// allowed and weak, or "". Excluded ciphers, as in "!RC4", are ignored.
+func weakCipher(list string) string {
+	for _, cipher := range strings.FieldsFunc(list, func(r rune) bool { return r == ':' || r == ' ' || r == ',' }) {
+		if strings.HasPrefix(cipher, "!") || strings.HasPrefix(cipher, "-") {
+			continue
+		}
+		name := strings.ToUpper(strings.TrimPrefix(cipher, "+"))
+		for _, weak := range weakCiphers {
+			if strings.Contains(name, weak) {
+				return cipher
+			}
+		}
+	}
+	return ""
+}
+
+// locationPrefix returns the path of a prefix location, or "" for exact
+// and regular expression locations
+func locationPrefix(location *nginx.Directive) string {
+	switch {
+	case len(location.Args) == 1 && !strings.HasPrefix(location.Args[0], "@"):
+		return location.Args[0]
+	case len(location.Args) == 2 && location.Args[0] == "^~":
+		return location.Args[1]
+	}
+	return ""
+}
+
+// withinPrefix reports whether a location path is allowed by prefix,
+// matching whole path segments so that "/files" allows "/files/a" but
+// not "/files-private"
+func withinPrefix(path, prefix string) bool {
+	switch {
+	case path == "" || prefix == "":
+		return false
+	case path == prefix:
+		return true
+	case strings.HasSuffix(prefix, "/"):
+		return strings.HasPrefix(path, prefix)
+	}
+	return strings.HasPrefix(path, prefix+"/")
+}
+
+// isTLSServer reports whether an http server accepts TLS connections
+func isTLSServer(t *Tree, server *nginx.Directive) bool {
+	for _, child := range t.Children(server) {
+		switch {
+		case child.Name == "listen" && (containsString(child.Args, "ssl") || containsString(child.Args, "quic")):
+			return true
+		case child.Name == "ssl" && len(child.Args) == 1 && strings.EqualFold(child.Args[0], "on"):
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	Register(&rule{
+		id:          "server-tokens",
+		description: "server_tokens on, which discloses the nginx version in headers and error pages",
+		severity:    Warning,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			if d.Name != "server_tokens" || len(d.Args) != 1 || !strings.EqualFold(d.Args[0], "on") {
+				return nil
+			}
+			diag := r.report(d, "server_tokens on discloses the nginx version")
+			diag.Suggestion = "server_tokens off"
+			return []Diagnostic{diag}
+		},
+	})
+
+	Register(&rule{
+		id:          "autoindex",
+		description: "autoindex on outside the locations allowed with -autoindex-allow",
+		severity:    Warning,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			if d.Name != "autoindex" || len(d.Args) != 1 || !strings.EqualFold(d.Args[0], "on") {
+				return nil
+			}
+			if location := ctx.Enclosing("location"); location != nil {
+				path := locationPrefix(location)
+				for _, allowed := range ctx.Tree.AutoindexAllow {
+					if withinPrefix(path, allowed) {
+						return nil
+					}
+				}
+			}
+			return []Diagnostic{r.report(d, "autoindex on lists directory contents")}
+		},
+	})
+
+	Register(&rule{
+		id:          "ssl-protocols",
+		description: "ssl_protocols enabling SSLv2, SSLv3, TLSv1 or TLSv1.1",
+		severity:    Error,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			if d.Name != "ssl_protocols" && d.Name != "proxy_ssl_protocols" {
+				return nil
+			}
+			var insecure []string
+			for _, arg := range d.Args {
+				if containsString(insecureProtocols, arg) {
+					insecure = append(insecure, arg)
+				}
+			}
+			if len(insecure) == 0 {
+				return nil
+			}
+			diag := r.report(d, "%s enables insecure protocol(s) %s", d.Name, strings.Join(insecure, ", "))
+			diag.Suggestion = d.Name + " TLSv1.2 TLSv1.3"
+			return []Diagnostic{diag}
+		},
+	})
+
+	Register(&rule{
+		id:          "ssl-ciphers",
+		description: "ssl_ciphers allowing null, anonymous, export, RC4, DES or MD5 ciphers",
+		severity:    Error,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			if (d.Name != "ssl_ciphers" && d.Name != "proxy_ssl_ciphers") || len(d.Args) != 1 {
+				return nil
+			}
+			if cipher := weakCipher(d.Args[0]); cipher != "" {
+				return []Diagnostic{r.report(d, "%s allows weak cipher %q", d.Name, cipher)}
+			}
+			return nil
+		},
+	})
+
+	Register(&rule{
+		id:          "hsts",
+		description: "TLS servers that do not send Strict-Transport-Security",
+		severity:    Warning,
+		contexts:    []string{"http"},
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			if d.Name != "server" || !isTLSServer(ctx.Tree, d) {
+				return nil
+			}
+			effective := effectiveHeaders(ctx.Tree, append(ctx.Parents[:len(ctx.Parents):len(ctx.Parents)], d))
+			if setsHeader(effective, "Strict-Transport-Security") {
+				return nil
+			}
+			diag := r.report(d, "TLS server does not send Strict-Transport-Security")
+			diag.Suggestion = "add_header Strict-Transport-Security 'max-age=31536000' always"
+			return []Diagnostic{diag}
+		},
+	})
+
+	Register(&rule{
+		id:          "add-header-always",
+		description: "add_header without always, so the header is missing from error responses",
+		severity:    Info,
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			if d.Name != "add_header" || len(d.Args) < 2 || d.Args[len(d.Args)-1] == "always" {
+				return nil
+			}
+			diag := r.report(d, "add_header %s is only sent with 2xx and 3xx responses", d.Args[0])
+			diag.Suggestion = d.Name + " " + strings.Join(d.Args, " ") + " always"
+			return []Diagnostic{diag}
+		},
+	})
+
+	Register(&rule{
+		id:          "alias-traversal",
+		description: "alias ending in \"/\" in a location that does not, which exposes the parent directory",
+		severity:    Error,
+		contexts:    []string{"location"},
+		check: func(r *rule, d *nginx.Directive, ctx *Context) []Diagnostic {
+			if d.Name != "alias" || len(d.Args) != 1 || !strings.HasSuffix(d.Args[0], "/") {
+				return nil
+			}
+			path := locationPrefix(ctx.Parent())
+			if path == "" || strings.HasSuffix(path, "/") {
+				return nil
+			}
+			diag := r.report(d, "location %s without a trailing \"/\" lets %s../ reach outside %s", path, path, d.Args[0])
+			diag.Suggestion = "location " + path + "/"
+			return []Diagnostic{diag}
+		},
+	})
+
+	RegisterSet("security", "server-tokens", "autoindex", "ssl-protocols", "ssl-ciphers", "hsts", "add-header-always", "alias-traversal")
+}
@@ -0,0 +1,130 @@
+package lint
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSecurity(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name:  "server_tokens",
+			files: map[string]string{"nginx.conf": "http {\n  server_tokens on;\n  server {\n    server_tokens off;\n  }\n}\n"},
+			want:  []string{"nginx.conf:2 server-tokens"},
+		},
+		{
+			name:  "autoindex",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    location /files/ {\n      autoindex on;\n    }\n    location / {\n      autoindex off;\n    }\n  }\n}\n"},
+			want:  []string{"nginx.conf:4 autoindex"},
+		},
+		{
+			name:  "insecure protocols",
+			files: map[string]string{"nginx.conf": "http {\n  ssl_protocols TLSv1 TLSv1.1 TLSv1.2;\n  server {\n    ssl_protocols TLSv1.2 TLSv1.3;\n  }\n}\n"},
+			want:  []string{"nginx.conf:2 ssl-protocols"},
+		},
+		{
+			name:  "weak ciphers",
+			files: map[string]string{"nginx.conf": "http {\n  ssl_ciphers HIGH:!aNULL:!MD5:!RC4;\n  server {\n    ssl_ciphers ECDHE-RSA-AES128-GCM-SHA256:DES-CBC3-SHA;\n  }\n}\n"},
+			want:  []string{"nginx.conf:4 ssl-ciphers"},
+		},
+		{
+			name:  "missing hsts",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    listen 443 ssl;\n  }\n  server {\n    listen 80;\n  }\n}\n"},
+			want:  []string{"nginx.conf:2 hsts"},
+		},
+		{
+			name:  "hsts inherited from http",
+			files: map[string]string{"nginx.conf": "http {\n  add_header Strict-Transport-Security max-age=31536000 always;\n  server {\n    listen 443 ssl;\n  }\n}\n"},
+		},
+		{
+			name:  "hsts shadowed by a server header",
+			files: map[string]string{"nginx.conf": "http {\n  add_header Strict-Transport-Security max-age=31536000 always;\n  server {\n    listen 443 ssl;\n    add_header X-Frame-Options DENY always;\n  }\n}\n"},
+			want:  []string{"nginx.conf:3 hsts"},
+		},
+		{
+			name: "hsts from an include",
+			files: map[string]string{
+				"nginx.conf": "http {\n  server {\n    listen 443 ssl;\n    include hsts.conf;\n  }\n}\n",
+				"hsts.conf":  "add_header Strict-Transport-Security max-age=31536000 always;\n",
+			},
+		},
+		{
+			name:  "add_header without always",
+			files: map[string]string{"nginx.conf": "http {\n  add_header X-Frame-Options DENY;\n  add_header X-Content-Type-Options nosniff always;\n}\n"},
+			want:  []string{"nginx.conf:2 add-header-always"},
+		},
+		{
+			name:  "alias traversal",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    location /static {\n      alias /srv/static/;\n    }\n    location /img/ {\n      alias /srv/img/;\n    }\n    location ~ ^/x {\n      alias /srv/x/;\n    }\n  }\n}\n"},
+			want:  []string{"nginx.conf:4 alias-traversal"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintFiles(t, tt.files, "security")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutoindexAllow(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf": "http {\n  server {\n    location /pub/files/ {\n      autoindex on;\n    }\n    location /private/ {\n      autoindex on;\n    }\n    location /mirror-private/ {\n      autoindex on;\n    }\n  }\n}\n",
+	})
+	tree := Load("", filepath.Join(root, "nginx.conf"))
+	tree.AutoindexAllow = []string{"/pub/", "/private", "/mirror"}
+
+	l, err := New([]string{"autoindex"}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	diagnostics := l.Lint(tree)
+	if len(diagnostics) != 1 || diagnostics[0].Line != 10 {
+		t.Errorf("Lint() = %v, want one finding on line 10", diagnostics)
+	}
+}
+
+func TestWithinPrefix(t *testing.T) {
+	tests := []struct {
+		path, prefix string
+		want         bool
+	}{
+		{"/downloads", "/downloads", true},
+		{"/downloads/", "/downloads", true},
+		{"/downloads/iso/", "/downloads", true},
+		{"/downloads/iso/", "/downloads/", true},
+		{"/downloads-private/", "/downloads", false},
+		{"/downloadsx", "/downloads", false},
+		{"/downloads", "/downloads/", false},
+		{"", "/", false},
+	}
+	for _, tt := range tests {
+		if got := withinPrefix(tt.path, tt.prefix); got != tt.want {
+			t.Errorf("withinPrefix(%q, %q) = %v, want %v", tt.path, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestWeakCipher(t *testing.T) {
+	tests := map[string]string{
+		"HIGH:!aNULL:!MD5":                      "",
+		"ECDHE-ECDSA-AES128-GCM-SHA256":         "",
+		"ECDHE-RSA-AES128-SHA:RC4-SHA":          "RC4-SHA",
+		"ALL:+EXP":                              "+EXP",
+		"EECDH+AESGCM:EDH+AESGCM:-DES-CBC3-SHA": "",
+		"aNULL":                                 "aNULL",
+	}
+	for list, want := range tests {
+		if got := weakCipher(list); got != want {
+			t.Errorf("weakCipher(%q) = %q, want %q", list, got, want)
+		}
+	}
+}
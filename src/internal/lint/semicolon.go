@@ -0,0 +1,186 @@
+package lint
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// insertion is a place a semicolon is missing: after the word ending a
+// directive, before the token following it on the same line if any
+type insertion struct {
+	line   int
+	column int // 0 for the end of the line
+}
+
+// missingSemicolons finds directives that are not terminated where that is
+// unambiguous: before a closing brace or the end of the file, and where a
+// line starts with a known directive name that would otherwise become an
+// argument of the directive above it, leaving that one with arguments the
+// schema does not allow
+func missingSemicolons(content []byte, schema *Schema) []insertion {
+	tokens, err := nginx.Lex(bytes.NewReader(content))
+	if err != nil {
+		return nil
+	}
+
+	var found []insertion
+	var words []nginx.Token
+	end := func(terminator *nginx.Token) {
+		for len(words) > 0 {
+			k := splitPoint(words, terminator, schema)
+			if k == len(words) {
+				break
+			}
+			found = append(found, insertAfter(words[k-1], tokens))
+			words = words[k:]
+		}
+		// a word ending in '}' at the end of the file is more likely a
+		// brace that lost its space than a missing semicolon
+		last := len(words) - 1
+		if last >= 0 && terminator == nil && !words[last].Quoted && strings.HasSuffix(words[last].Value, "}") {
+			last = -1
+		}
+		if last >= 0 && (terminator == nil || terminator.Kind == nginx.TokenBlockEnd) {
+			found = append(found, insertAfter(words[last], tokens))
+		}
+		words = nil
+	}
+
+	for i, tok := range tokens {
+		switch tok.Kind {
+		case nginx.TokenWord:
+			words = append(words, tok)
+		case nginx.TokenSemicolon, nginx.TokenBlockStart, nginx.TokenBlockEnd:
+			end(&tokens[i])
+		}
+	}
+	end(nil)
+
+	return found
+}
+
+// splitPoint returns the index of the first word that should start a new
+// directive, or len(words) if the statement is fine as it is
+func splitPoint(words []nginx.Token, terminator *nginx.Token, schema *Schema) int {
+	name := words[0].Value
+	block := terminator != nil && terminator.Kind == nginx.TokenBlockStart
+	if !schema.Known(name) || validArgs(schema, name, args(words), block) {
+		return len(words)
+	}
+
+	for k := 1; k < len(words); k++ {
+		w := words[k]
+		if w.Quoted || w.Line == words[k-1].Line || !schema.Known(w.Value) {
+			continue
+		}
+		if validArgs(schema, name, args(words[:k]), false) {
+			return k
+		}
+	}
+	return len(words)
+}
+
+func args(words []nginx.Token) []string {
+	values := make([]string, 0, len(words)-1)
+	for _, w := range words[1:] {
+		values = append(values, w.Value)
+	}
+	return values
+}
+
+// validArgs reports whether any form of a directive accepts args
+func validArgs(schema *Schema, name string, args []string, block bool) bool {
+	for _, spec := range schema.Directives[name] {
+		if spec.Block == block && spec.checkArgs(args) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// insertAfter places a semicolon after word, before whatever follows it on
+// the same line
+func insertAfter(word nginx.Token, tokens []nginx.Token) insertion {
+	ins := insertion{line: word.Line}
+	for i, tok := range tokens {
+		if tok.Line == word.Line && tok.Column == word.Column && i+1 < len(tokens) && tokens[i+1].Line == word.Line {
+			ins.column = tokens[i+1].Column
+		}
+	}
+	return ins
+}
+
+// insertSemicolons applies insertions to content
+func insertSemicolons(content []byte, insertions []insertion) []byte {
+	lines := strings.Split(string(content), "\n")
+	// later insertions on a line first so earlier columns stay valid
+	for i := len(insertions) - 1; i >= 0; i-- {
+		ins := insertions[i]
+		line := []rune(lines[ins.line-1])
+		if ins.column == 0 {
+			lines[ins.line-1] = strings.TrimRight(string(line), " \t\r") + ";" + trailingCR(string(line))
+			continue
+		}
+		before := strings.TrimRight(string(line[:ins.column-1]), " \t")
+		lines[ins.line-1] = before + "; " + string(line[ins.column-1:])
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+func trailingCR(line string) string {
+	if strings.HasSuffix(line, "\r") {
+		return "\r"
+	}
+	return ""
+}
+
+// sourceFiles returns every file of the tree, including those that failed
+// to parse
+func sourceFiles(t *Tree) []string {
+	files := append([]string{}, t.Graph.Files...)
+	for _, err := range t.Graph.Errors {
+		var parseErr *nginx.ParseError
+		if errors.As(err, &parseErr) && parseErr.File != "" && !containsString(files, parseErr.File) {
+			files = append(files, parseErr.File)
+		}
+	}
+	return files
+}
+
+func init() {
+	Register(&rule{
+		id:          "missing-semicolon",
+		description: "directives that are not terminated by \";\" where the intent is unambiguous",
+		severity:    Error,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			var diagnostics []Diagnostic
+			for _, file := range sourceFiles(t) {
+				content, err := t.Graph.ReadFile(file)
+				if err != nil {
+					continue
+				}
+				for _, ins := range missingSemicolons(content, t.Schema) {
+					diagnostics = append(diagnostics, r.reportAt(file, ins.line, "missing \";\""))
+				}
+			}
+			return diagnostics
+		},
+		fixTree: func(r *rule, t *Tree, e *Editor) error {
+			for _, file := range sourceFiles(t) {
+				content, err := e.Text(file)
+				if err != nil {
+					return err
+				}
+				if insertions := missingSemicolons(content, t.Schema); len(insertions) > 0 {
+					if err := e.SetText(file, insertSemicolons(content, insertions)); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,127 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// disablePattern matches "# gofmtnginx:disable=rule-a,rule-b". The rule
+// name "all" disables every rule.
+var disablePattern = regexp.MustCompile(`^\s*gofmtnginx:disable=([\w,-]+)`)
+
+type position struct {
+	file string
+	line int
+}
+
+func (p position) String() string {
+	return fmt.Sprintf("%s:%d", p.file, p.line)
+}
+
+// suppression records the rules disabled at each directive position
+type suppression map[position]map[string]bool
+
+func (s suppression) covers(d Diagnostic) bool {
+	rules := s[position{d.File, d.Line}]
+	return rules["all"] || rules[d.Rule]
+}
+
+// suppressions finds the disable comments in t. A comment on the same line
+// as a directive, or after the "{" of a block, applies to that directive;
+// comments on the lines directly above a directive apply to it too. Either
+// way a block directive passes them on to everything inside it.
+func suppressions(t *Tree) suppression {
+	s := make(suppression)
+	inherited := make(map[*nginx.Directive]map[string]bool)
+
+	t.walk(t.Directives(), nil, func(d *nginx.Directive, parents, siblings []*nginx.Directive) {
+		if d.IsComment() {
+			return
+		}
+
+		rules := make(map[string]bool)
+		if len(parents) > 0 {
+			for rule := range inherited[parents[len(parents)-1]] {
+				rules[rule] = true
+			}
+		}
+		for _, rule := range disabledBy(d, siblings) {
+			rules[rule] = true
+		}
+		if len(rules) == 0 {
+			return
+		}
+
+		inherited[d] = rules
+		pos := position{d.File, d.Line}
+		if s[pos] == nil {
+			s[pos] = make(map[string]bool)
+		}
+		for rule := range rules {
+			s[pos][rule] = true
+		}
+	})
+
+	return s
+}
+
+// disabledBy returns the rules disabled for d by comments among its siblings
+func disabledBy(d *nginx.Directive, siblings []*nginx.Directive) []string {
+	var rules []string
+	for i, n := range siblings {
+		if n != d {
+			continue
+		}
+
+		// a trailing comment on the directive's last line
+		if i+1 < len(siblings) {
+			next := siblings[i+1]
+			if next.IsComment() && next.File == d.File && next.Line == d.EndLine {
+				rules = append(rules, disabled(next)...)
+			}
+		}
+
+		// a comment after the opening brace of a block
+		if d.IsBlock() && len(d.Block) > 0 {
+			first := d.Block[0]
+			if first.IsComment() && first.File == d.File && first.Line == d.Line {
+				rules = append(rules, disabled(first)...)
+			}
+		}
+
+		// comments on the lines directly above it, with no blank line or
+		// directive in between
+		below := d.Line
+		for j := i - 1; j >= 0 && siblings[j].IsComment(); j-- {
+			c := siblings[j]
+			if c.File != d.File || c.EndLine != below-1 {
+				break
+			}
+			if j > 0 && siblings[j-1].File == c.File && siblings[j-1].EndLine == c.Line {
+				// belongs to the previous directive
+				break
+			}
+			rules = append(rules, disabled(c)...)
+			below = c.Line
+		}
+		break
+	}
+	return rules
+}
+
+func disabled(comment *nginx.Directive) []string {
+	m := disablePattern.FindStringSubmatch(comment.Comment)
+	if m == nil {
+		return nil
+	}
+	var rules []string
+	for _, rule := range strings.Split(m[1], ",") {
+		if rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
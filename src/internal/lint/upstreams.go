@@ -0,0 +1,158 @@
+package lint
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// passDirectives are the directives that send requests to a server or
+// upstream group
+var passDirectives = []string{"proxy_pass", "fastcgi_pass", "grpc_pass", "uwsgi_pass"}
+
+// upstreamRef is a pass directive naming an upstream group
+type upstreamRef struct {
+	directive *nginx.Directive
+	name      string
+}
+
+// upstreams records the upstream groups of a configuration and the pass
+// directives referring to them, keyed by module ("http" or "stream") as
+// the two have separate groups
+type upstreams struct {
+	defined map[string][]*nginx.Directive
+	refs    []upstreamRef
+	// mapped holds the values of map entries, which name upstreams when
+	// a pass target is a variable
+	mapped map[string]bool
+}
+
+// passHost returns the host part of a pass target: the scheme, port and
+// URI are removed. It returns "" for UNIX sockets and targets holding
+// variables, which are only known at run time.
+func passHost(target string) string {
+	if _, rest, ok := strings.Cut(target, "://"); ok {
+		target = rest
+	}
+	if strings.HasPrefix(target, "unix:") || strings.Contains(target, "$") {
+		return ""
+	}
+	target, _, _ = strings.Cut(target, "/")
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return host
+	}
+	return target
+}
+
+// namesUpstream reports whether a pass target looks like an upstream
+// group: a bare name without a port, as opposed to an address or a domain
+// name. nginx resolves a bare name with no upstream group through DNS, as
+// it does for the service names of a container network.
+func namesUpstream(target, host string) bool {
+	if host == "" || host == "localhost" || net.ParseIP(host) != nil || strings.Contains(host, ".") {
+		return false
+	}
+	_, rest, _ := strings.Cut(target, "://")
+	if rest == "" {
+		rest = target
+	}
+	rest, _, _ = strings.Cut(rest, "/")
+	return rest == host
+}
+
+func moduleOf(ctx *Context) string {
+	if ctx.Enclosing("stream") != nil {
+		return "stream"
+	}
+	return "http"
+}
+
+// analyzeUpstreams collects the upstream groups of t and the pass
+// directives that refer to one
+func analyzeUpstreams(t *Tree) *upstreams {
+	u := &upstreams{defined: make(map[string][]*nginx.Directive), mapped: make(map[string]bool)}
+	t.Walk(func(d *nginx.Directive, ctx *Context) {
+		switch {
+		case d.Name == "upstream" && len(d.Args) == 1:
+			key := moduleOf(ctx) + " " + d.Args[0]
+			if !containsDirective(u.defined[key], d) {
+				u.defined[key] = append(u.defined[key], d)
+			}
+		case d.Name == "map" && d.IsBlock():
+			for _, entry := range t.Children(d) {
+				for _, arg := range entry.Args {
+					u.mapped[arg] = true
+				}
+			}
+		case len(d.Args) > 0 && containsString(passDirectives, d.Name):
+			if host := passHost(d.Args[0]); host != "" {
+				u.refs = append(u.refs, upstreamRef{directive: d, name: moduleOf(ctx) + " " + host})
+			}
+		}
+	})
+	return u
+}
+
+func init() {
+	Register(&rule{
+		id:          "upstream-undefined",
+		description: "proxy_pass, fastcgi_pass, grpc_pass and uwsgi_pass naming an upstream that is not defined, which nginx looks up in DNS instead",
+		severity:    Warning,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			u := analyzeUpstreams(t)
+			var diagnostics []Diagnostic
+			for _, ref := range u.refs {
+				module, host, _ := strings.Cut(ref.name, " ")
+				if len(u.defined[ref.name]) > 0 || !namesUpstream(ref.directive.Args[0], host) {
+					continue
+				}
+				diag := r.report(ref.directive, "%s refers to %q, but there is no upstream named %q in %s; nginx will resolve it through DNS", ref.directive.Name, host, host, module)
+				other := map[string]string{"http": "stream", "stream": "http"}[module]
+				if defs := u.defined[other+" "+host]; len(defs) > 0 {
+					diag.Message += fmt.Sprintf("; the one at %s:%d is in %s", defs[0].File, defs[0].Line, other)
+				}
+				diagnostics = append(diagnostics, diag)
+			}
+			return diagnostics
+		},
+	})
+
+	Register(&rule{
+		id:          "upstream-unused",
+		description: "upstream blocks no pass directive or map refers to",
+		severity:    Warning,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			u := analyzeUpstreams(t)
+			referenced := make(map[string]bool)
+			for _, ref := range u.refs {
+				referenced[ref.name] = true
+			}
+			var diagnostics []Diagnostic
+			for key, defs := range u.defined {
+				_, name, _ := strings.Cut(key, " ")
+				if !referenced[key] && !u.mapped[name] {
+					diagnostics = append(diagnostics, r.report(defs[0], "upstream %q is never used", name))
+				}
+			}
+			return diagnostics
+		},
+	})
+
+	Register(&rule{
+		id:          "upstream-duplicate",
+		description: "upstream blocks defined more than once with the same name",
+		severity:    Error,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			u := analyzeUpstreams(t)
+			var diagnostics []Diagnostic
+			for _, defs := range u.defined {
+				for _, d := range defs[1:] {
+					diagnostics = append(diagnostics, r.report(d, "upstream %q is already defined at %s:%d", d.Args[0], defs[0].File, defs[0].Line))
+				}
+			}
+			return diagnostics
+		},
+	})
+}
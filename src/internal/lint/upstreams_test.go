@@ -0,0 +1,101 @@
+package lint
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpstreams(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		rules []string
+		want  []string
+	}{
+		{
+			name: "references across includes",
+			files: map[string]string{
+				"nginx.conf":     "http {\n  include upstreams.conf;\n  server {\n    location / {\n      proxy_pass http://app/;\n    }\n    location /rpc {\n      grpc_pass grpc://rpc;\n    }\n    location ~ \\.php$ {\n      fastcgi_pass php;\n    }\n    location /py {\n      uwsgi_pass uwsgi://py;\n    }\n  }\n}\n",
+				"upstreams.conf": "upstream app {\n  server 127.0.0.1:8080;\n}\nupstream rpc {\n  server 127.0.0.1:9090;\n}\nupstream php {\n  server unix:/run/php.sock;\n}\nupstream py {\n  server 127.0.0.1:3031;\n}\n",
+			},
+			rules: []string{"upstream-undefined", "upstream-unused", "upstream-duplicate"},
+		},
+		{
+			name:  "undefined upstream",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    location / {\n      proxy_pass http://backnd;\n    }\n    location /a {\n      proxy_pass http://127.0.0.1:8080;\n    }\n    location /b {\n      proxy_pass https://api.example.com/v1/;\n    }\n    location /c {\n      proxy_pass http://$target;\n    }\n    location /d {\n      fastcgi_pass unix:/run/php.sock;\n    }\n    location /e {\n      proxy_pass http://localhost;\n    }\n  }\n}\n"},
+			rules: []string{"upstream-undefined"},
+			want:  []string{"nginx.conf:4 upstream-undefined"},
+		},
+		{
+			name:  "stream and http are separate",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    location / {\n      proxy_pass http://db;\n    }\n  }\n}\nstream {\n  upstream db {\n    server 10.0.0.1:5432;\n  }\n  server {\n    listen 5432;\n    proxy_pass db;\n  }\n}\n"},
+			rules: []string{"upstream-undefined", "upstream-unused"},
+			want:  []string{"nginx.conf:4 upstream-undefined"},
+		},
+		{
+			name:  "unused upstream",
+			files: map[string]string{"nginx.conf": "http {\n  upstream old {\n    server 10.0.0.1;\n  }\n  upstream blue {\n    server 10.0.0.2;\n  }\n  map $cookie_slot $pool {\n    default blue;\n  }\n  server {\n    location / {\n      proxy_pass http://$pool;\n    }\n  }\n}\n"},
+			rules: []string{"upstream-unused"},
+			want:  []string{"nginx.conf:2 upstream-unused"},
+		},
+		{
+			name: "defined twice",
+			files: map[string]string{
+				"nginx.conf":      "http {\n  upstream app {\n    server 10.0.0.1;\n  }\n  include conf.d/*.conf;\n  server {\n    location / {\n      proxy_pass http://app;\n    }\n  }\n}\n",
+				"conf.d/app.conf": "upstream app {\n  server 10.0.0.2;\n}\n",
+			},
+			rules: []string{"upstream-duplicate"},
+			want:  []string{"conf.d/app.conf:1 upstream-duplicate"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintFiles(t, tt.files, tt.rules...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpstreamFindingsNameBothFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf": "http {\n  upstream app {\n    server 10.0.0.1;\n  }\n  include app.conf;\n}\n",
+		"app.conf":   "upstream app {\n  server 10.0.0.2;\n}\n",
+	})
+	l, err := New([]string{"upstream-duplicate"}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	diagnostics := l.Lint(Load("", filepath.Join(root, "nginx.conf")))
+	if len(diagnostics) != 1 {
+		t.Fatalf("Lint() = %v, want one finding", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.File != filepath.Join(root, "app.conf") || !strings.Contains(d.Message, filepath.Join(root, "nginx.conf")+":2") {
+		t.Errorf("Lint() = %v, want a finding in app.conf naming nginx.conf:2", d)
+	}
+}
+
+func TestUndefinedUpstreamIsWarning(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf": "http {\n  server {\n    location / {\n      proxy_pass http://app;\n    }\n  }\n}\n",
+	})
+	l, err := New([]string{"upstream-undefined"}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	diagnostics := l.Lint(Load("", filepath.Join(root, "nginx.conf")))
+	if len(diagnostics) != 1 {
+		t.Fatalf("Lint() = %v, want one finding", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Severity != Warning || !strings.Contains(d.Message, "resolve it through DNS") {
+		t.Errorf("Lint() = %v, want a warning that app is resolved through DNS", d)
+	}
+}
@@ -0,0 +1,203 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+var (
+	variablePattern = regexp.MustCompile(`\$(?:\{([A-Za-z0-9_]+)\}|([A-Za-z0-9_]+))`)
+	capturePattern  = regexp.MustCompile(`\(\?P?(?:<([A-Za-z_][A-Za-z0-9_]*)>|'([A-Za-z_][A-Za-z0-9_]*)')`)
+	regexOperators  = []string{"~", "~*", "!~", "!~*"}
+)
+
+// definingDirectives maps the directives that define a variable to the
+// position of its name in their arguments, -1 being the last
+var definingDirectives = map[string]int{
+	"set":              0,
+	"map":              1,
+	"geo":              -1,
+	"split_clients":    1,
+	"js_set":           0,
+	"js_var":           0,
+	"perl_set":         0,
+	"auth_request_set": 0,
+}
+
+// variables records where the variables of a configuration are defined
+// and used. Names are lowercase, without "$", as nginx compares them.
+type variables struct {
+	defined map[string][]*nginx.Directive
+	used    map[string]bool
+	// uses lists each directive referring to a variable with the names it
+	// refers to, in configuration order
+	uses []variableUse
+}
+
+type variableUse struct {
+	directive *nginx.Directive
+	names     []string
+}
+
+// definedArg returns the index of the argument naming the variable d
+// defines, or -1
+func definedArg(d *nginx.Directive) int {
+	pos, ok := definingDirectives[d.Name]
+	if !ok || len(d.Args) == 0 {
+		return -1
+	}
+	if pos < 0 {
+		pos = len(d.Args) - 1
+	}
+	if pos >= len(d.Args) || !strings.HasPrefix(d.Args[pos], "$") {
+		return -1
+	}
+	return pos
+}
+
+// isRegex reports whether argument i of d is a regular expression, whose
+// "$" anchors are not variables but whose named captures define some
+func isRegex(d *nginx.Directive, i int) bool {
+	switch {
+	case strings.HasPrefix(d.Args[i], "~"):
+		return true
+	case d.Name == "location":
+		return i == 1 && containsString(regexOperators, d.Args[0])
+	case d.Name == "rewrite":
+		return i == 0
+	case d.Name == "if":
+		return i == 2 && containsString(regexOperators, d.Args[1])
+	}
+	return false
+}
+
+// analyzeVariables collects the variable definitions and uses of t,
+// including the entries of map blocks
+func analyzeVariables(t *Tree) *variables {
+	v := &variables{defined: make(map[string][]*nginx.Directive), used: make(map[string]bool)}
+
+	scan := func(d *nginx.Directive, skipName bool) {
+		var names []string
+		if !skipName {
+			// map keys can be regular expressions with named captures
+			for _, m := range capturePattern.FindAllStringSubmatch(d.Name, -1) {
+				v.define(m[1]+m[2], d)
+			}
+		}
+		defines := definedArg(d)
+		for i, arg := range d.Args {
+			if i == defines {
+				v.define(strings.Trim(arg[1:], "{}"), d)
+				continue
+			}
+			if isRegex(d, i) {
+				for _, m := range capturePattern.FindAllStringSubmatch(arg, -1) {
+					v.define(m[1]+m[2], d)
+				}
+				continue
+			}
+			for _, m := range variablePattern.FindAllStringSubmatch(arg, -1) {
+				name := strings.ToLower(m[1] + m[2])
+				names = append(names, name)
+				v.used[name] = true
+			}
+		}
+		if len(names) > 0 {
+			v.uses = append(v.uses, variableUse{directive: d, names: names})
+		}
+	}
+
+	t.Walk(func(d *nginx.Directive, ctx *Context) {
+		scan(d, true)
+		if d.Name == "map" && d.IsBlock() {
+			for _, entry := range t.Children(d) {
+				if !entry.IsComment() {
+					scan(entry, false)
+				}
+			}
+		}
+	})
+	return v
+}
+
+func (v *variables) define(name string, d *nginx.Directive) {
+	name = strings.ToLower(name)
+	if !containsDirective(v.defined[name], d) {
+		v.defined[name] = append(v.defined[name], d)
+	}
+}
+
+func containsDirective(directives []*nginx.Directive, d *nginx.Directive) bool {
+	for _, n := range directives {
+		if n == d {
+			return true
+		}
+	}
+	return false
+}
+
+// numbered reports whether a variable is a numbered regex capture
+func numbered(name string) bool {
+	return strings.Trim(name, "0123456789") == ""
+}
+
+// unused reports the definitions made by the directive name that no
+// directive reads
+func unused(r *rule, t *Tree, directive, kind string) []Diagnostic {
+	v := analyzeVariables(t)
+	var diagnostics []Diagnostic
+	for name, defs := range v.defined {
+		if v.used[name] || t.Schema.Builtin(name) {
+			continue
+		}
+		for _, d := range defs {
+			if d.Name == directive {
+				diagnostics = append(diagnostics, r.report(d, "%s $%s is never used", kind, name))
+			}
+		}
+	}
+	return diagnostics
+}
+
+func init() {
+	Register(&rule{
+		id:          "undefined-variable",
+		description: "variables that are neither built in nor defined by set, map, geo, split_clients, js_set or a regex capture",
+		severity:    Error,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			v := analyzeVariables(t)
+			var diagnostics []Diagnostic
+			for _, use := range v.uses {
+				reported := make(map[string]bool)
+				for _, name := range use.names {
+					if reported[name] || numbered(name) || len(v.defined[name]) > 0 || t.Schema.Builtin(name) {
+						continue
+					}
+					reported[name] = true
+					diagnostics = append(diagnostics, r.report(use.directive, "$%s is not defined and evaluates to an empty string", name))
+				}
+			}
+			return diagnostics
+		},
+	})
+
+	Register(&rule{
+		id:          "unused-map",
+		description: "map blocks whose variable is never used",
+		severity:    Warning,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			return unused(r, t, "map", "map output")
+		},
+	})
+
+	Register(&rule{
+		id:          "unused-set",
+		description: "variables assigned with set that are never read",
+		severity:    Warning,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			return unused(r, t, "set", "variable")
+		},
+	})
+}
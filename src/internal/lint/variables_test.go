@@ -0,0 +1,66 @@
+package lint
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVariables(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		rules []string
+		want  []string
+	}{
+		{
+			name:  "builtin and set variables",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    set $backend 127.0.0.1;\n    location / {\n      proxy_pass http://${backend}:8080$request_uri;\n      add_header X-Host $HOST;\n      add_header X-Agent $http_user_agent;\n    }\n  }\n}\n"},
+			rules: []string{"undefined-variable", "unused-set"},
+		},
+		{
+			name:  "typo",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    location / {\n      return 200 \"$reqeust_uri $uri\";\n    }\n  }\n}\n"},
+			rules: []string{"undefined-variable"},
+			want:  []string{"nginx.conf:4 undefined-variable"},
+		},
+		{
+			name: "defined in an include",
+			files: map[string]string{
+				"nginx.conf": "http {\n  include maps.conf;\n  log_format main '$remote_addr $is_bot $variant $country';\n}\n",
+				"maps.conf":  "map $http_user_agent $is_bot {\n  default 0;\n}\nsplit_clients $remote_addr $variant {\n  50% a;\n  * b;\n}\ngeo $country {\n  default ZZ;\n}\n",
+			},
+			rules: []string{"undefined-variable", "unused-map"},
+		},
+		{
+			name:  "regex captures",
+			files: map[string]string{"nginx.conf": "http {\n  map $uri $section {\n    ~^/(?<top>[a-z]+)/ $top;\n  }\n  server {\n    server_name ~^(?P<sub>.+)\\.example\\.com$;\n    location ~ ^/u/(?<user>[^/]+)$ {\n      return 200 \"$user $sub $section $1\";\n    }\n  }\n}\n"},
+			rules: []string{"undefined-variable", "unused-map"},
+		},
+		{
+			name:  "js_set, js_var and perl_set",
+			files: map[string]string{"nginx.conf": "http {\n  js_set $summary main.summary;\n  js_var $tenant;\n  perl_set $upper Upper::handler;\n  server {\n    location / {\n      return 200 \"$summary $tenant $upper\";\n    }\n  }\n}\n"},
+			rules: []string{"undefined-variable", "unknown-directive", "arity"},
+		},
+		{
+			name:  "unused map output",
+			files: map[string]string{"nginx.conf": "http {\n  map $host $tenant {\n    default none;\n  }\n  map $host $used {\n    default $host;\n  }\n  server {\n    root /srv/$used;\n  }\n}\n"},
+			rules: []string{"unused-map"},
+			want:  []string{"nginx.conf:2 unused-map"},
+		},
+		{
+			name:  "set but never read",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    set $tmp 1;\n    set $limit_rate 10k;\n    set $read 1;\n    if ($read) {\n      return 403;\n    }\n  }\n}\n"},
+			rules: []string{"unused-set"},
+			want:  []string{"nginx.conf:3 unused-set"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintFiles(t, tt.files, tt.rules...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// Vhost is one name an http server answers to on one socket
+type Vhost struct {
+	// Socket is the address and port, such as "*:80" or "[::]:443", with
+	// "/udp" after it for a quic listener, such as "*:443/udp"
+	Socket string
+	// Name is the server name, lowercase, "" for requests without a Host
+	Name    string
+	Default bool
+	Server  *nginx.Directive
+	Listen  *nginx.Directive
+}
+
+// listenSocket returns the socket a listen directive binds, using the
+// defaults of nginx for a missing address or port
+func listenSocket(address string) string {
+	switch {
+	case strings.HasPrefix(address, "unix:"):
+		return address
+	case strings.HasPrefix(address, "["):
+		if end := strings.Index(address, "]"); end >= 0 {
+			if port, ok := strings.CutPrefix(address[end+1:], ":"); ok {
+				return address[:end+1] + ":" + port
+			}
+			return address[:end+1] + ":80"
+		}
+	case strings.Trim(address, "0123456789") == "":
+		return "*:" + address
+	}
+
+	host, port, ok := strings.Cut(address, ":")
+	if !ok {
+		port = "80"
+	}
+	if host == "0.0.0.0" {
+		host = "*"
+	}
+	return host + ":" + port
+}
+
+// vhostSocket returns the socket of a listen directive. A quic listener
+// binds a UDP socket, separate from a TCP one on the same address and port.
+func vhostSocket(listen *nginx.Directive) string {
+	socket := listenSocket(listen.Args[0])
+	if containsString(listen.Args[1:], "quic") {
+		socket += "/udp"
+	}
+	return socket
+}
+
+// serverListens returns the listen directives of a server, or nil if it
+// has none and listens on *:80
+func serverListens(t *Tree, server *nginx.Directive) []*nginx.Directive {
+	var listens []*nginx.Directive
+	for _, child := range t.Children(server) {
+		if child.Name == "listen" && len(child.Args) > 0 {
+			listens = append(listens, child)
+		}
+	}
+	return listens
+}
+
+// serverNames returns the names of a server, lowercase, with "" for a
+// server without server_name
+func serverNames(t *Tree, server *nginx.Directive) []string {
+	var names []string
+	found := false
+	for _, child := range t.Children(server) {
+		if child.Name != "server_name" {
+			continue
+		}
+		found = true
+		for _, name := range child.Args {
+			if name = strings.ToLower(name); !containsString(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	if !found {
+		names = []string{""}
+	}
+	return names
+}
+
+// Vhosts returns every socket and name pair of the http servers of t, in
+// configuration order. A server without listen is on *:80.
+func Vhosts(t *Tree) []Vhost {
+	var vhosts []Vhost
+	seen := make(map[*nginx.Directive]bool)
+	t.Walk(func(d *nginx.Directive, ctx *Context) {
+		if d.Name != "server" || ctx.Name != "http" || !d.IsBlock() || seen[d] {
+			return
+		}
+		seen[d] = true
+
+		listens := serverListens(t, d)
+		names := serverNames(t, d)
+		if len(listens) == 0 {
+			for _, name := range names {
+				vhosts = append(vhosts, Vhost{Socket: "*:80", Name: name, Server: d})
+			}
+			return
+		}
+		for _, listen := range listens {
+			def := containsString(listen.Args[1:], "default_server") || containsString(listen.Args[1:], "default")
+			for _, name := range names {
+				vhosts = append(vhosts, Vhost{Socket: vhostSocket(listen), Name: name, Default: def, Server: d, Listen: listen})
+			}
+		}
+	})
+	return vhosts
+}
+
+// bySocket groups vhosts by socket, keeping their order
+func bySocket(vhosts []Vhost) (map[string][]Vhost, []string) {
+	groups := make(map[string][]Vhost)
+	var sockets []string
+	for _, v := range vhosts {
+		if _, ok := groups[v.Socket]; !ok {
+			sockets = append(sockets, v.Socket)
+		}
+		groups[v.Socket] = append(groups[v.Socket], v)
+	}
+	sort.Strings(sockets)
+	return groups, sockets
+}
+
+// defaultServer returns the server requests to a socket go to when no
+// name matches: the one marked default_server, or else the first
+func defaultServer(group []Vhost) *nginx.Directive {
+	for _, v := range group {
+		if v.Default {
+			return v.Server
+		}
+	}
+	return group[0].Server
+}
+
+// where returns the file:line of d
+func where(d *nginx.Directive) string {
+	return fmt.Sprintf("%s:%d", d.File, d.Line)
+}
+
+func init() {
+	Register(&rule{
+		id:          "server-name-conflict",
+		description: "servers answering to the same name on the same address and port, of which nginx only ever uses the first",
+		severity:    Warning,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			groups, sockets := bySocket(Vhosts(t))
+			var diagnostics []Diagnostic
+			for _, socket := range sockets {
+				owner := make(map[string]*nginx.Directive)
+				for _, v := range groups[socket] {
+					first, ok := owner[v.Name]
+					switch {
+					case !ok:
+						owner[v.Name] = v.Server
+					case first != v.Server:
+						diagnostics = append(diagnostics, r.report(v.Server, "conflicting server name %q on %s, already used by the server at %s", v.Name, socket, where(first)))
+					}
+				}
+			}
+			return diagnostics
+		},
+	})
+
+	Register(&rule{
+		id:          "default-server",
+		description: "more than one default_server for the same address and port",
+		severity:    Error,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			groups, sockets := bySocket(Vhosts(t))
+			var diagnostics []Diagnostic
+			for _, socket := range sockets {
+				var first *Vhost
+				for i, v := range groups[socket] {
+					switch {
+					case !v.Default:
+					case first == nil:
+						first = &groups[socket][i]
+					case v.Listen != first.Listen:
+						diagnostics = append(diagnostics, r.report(v.Listen, "a duplicate default server for %s, already set at %s", socket, where(first.Listen)))
+					}
+				}
+			}
+			return diagnostics
+		},
+	})
+
+	Register(&rule{
+		id:          "unreachable-server",
+		description: "servers that are not the default anywhere they listen and whose names are all taken by earlier servers",
+		severity:    Warning,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			vhosts := Vhosts(t)
+			groups, _ := bySocket(vhosts)
+
+			reachable := make(map[*nginx.Directive]bool)
+			for _, group := range groups {
+				reachable[defaultServer(group)] = true
+				owner := make(map[string]*nginx.Directive)
+				for _, v := range group {
+					if _, ok := owner[v.Name]; !ok {
+						owner[v.Name] = v.Server
+						reachable[v.Server] = true
+					}
+				}
+			}
+
+			var diagnostics []Diagnostic
+			reported := make(map[*nginx.Directive]bool)
+			for _, v := range vhosts {
+				if !reachable[v.Server] && !reported[v.Server] {
+					reported[v.Server] = true
+					diagnostics = append(diagnostics, r.report(v.Server, "server can never be selected: every name it has is used by an earlier server on the same address and port"))
+				}
+			}
+			return diagnostics
+		},
+	})
+}
@@ -0,0 +1,108 @@
+package lint
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestListenSocket(t *testing.T) {
+	tests := map[string]string{
+		"80":                 "*:80",
+		"*:8080":             "*:8080",
+		"0.0.0.0:443":        "*:443",
+		"127.0.0.1":          "127.0.0.1:80",
+		"10.0.0.1:8000":      "10.0.0.1:8000",
+		"localhost:80":       "localhost:80",
+		"[::]:443":           "[::]:443",
+		"[::1]":              "[::1]:80",
+		"unix:/run/app.sock": "unix:/run/app.sock",
+	}
+	for address, want := range tests {
+		if got := listenSocket(address); got != want {
+			t.Errorf("listenSocket(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
+
+func TestVhosts(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf":   "http {\n  server {\n    listen 80 default_server;\n    listen [::]:80;\n    server_name Example.com;\n  }\n  include sites/*.conf;\n}\nstream {\n  server {\n    listen 53;\n  }\n}\n",
+		"sites/a.conf": "server {\n  server_name a.example.com b.example.com;\n}\n",
+	})
+
+	var got []string
+	for _, v := range Vhosts(Load("", filepath.Join(root, "nginx.conf"))) {
+		rel, _ := filepath.Rel(root, v.Server.File)
+		got = append(got, v.Socket+" "+v.Name+" "+strconv.FormatBool(v.Default)+" "+rel+":"+strconv.Itoa(v.Server.Line))
+	}
+	want := []string{
+		"*:80 example.com true nginx.conf:2",
+		"[::]:80 example.com false nginx.conf:2",
+		"*:80 a.example.com false sites/a.conf:1",
+		"*:80 b.example.com false sites/a.conf:1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Vhosts() = %v, want %v", got, want)
+	}
+}
+
+func TestVhostRules(t *testing.T) {
+	rules := []string{"server-name-conflict", "default-server", "unreachable-server"}
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name:  "distinct names and sockets",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    listen 80 default_server;\n    return 444;\n  }\n  server {\n    listen 80;\n    server_name a.example.com;\n  }\n  server {\n    listen 8080;\n    server_name a.example.com;\n  }\n}\n"},
+		},
+		{
+			name: "conflict across files",
+			files: map[string]string{
+				"nginx.conf": "http {\n  server {\n    listen 80;\n    server_name a.example.com;\n  }\n  include b.conf;\n}\n",
+				"b.conf":     "server {\n  listen 0.0.0.0:80;\n  server_name a.example.com b.example.com;\n}\n",
+			},
+			want: []string{"b.conf:1 server-name-conflict"},
+		},
+		{
+			name:  "two default servers",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    listen 80 default_server;\n    server_name a;\n  }\n  server {\n    listen *:80 default_server;\n    server_name b;\n  }\n  server {\n    listen 81 default_server;\n    server_name c;\n  }\n}\n"},
+			want:  []string{"nginx.conf:7 default-server"},
+		},
+		{
+			name:  "quic and ssl default on one port",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    listen 443 ssl default_server;\n    listen 443 quic reuseport default_server;\n    server_name a;\n  }\n  server {\n    listen 443 ssl;\n    listen 443 quic;\n    server_name b;\n  }\n}\n"},
+		},
+		{
+			name:  "quic and ssl defaults in two servers",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    listen 443 ssl default_server;\n    server_name a;\n  }\n  server {\n    listen 443 quic default_server;\n    server_name b;\n  }\n}\n"},
+		},
+		{
+			name:  "two quic default servers",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    listen 443 quic default_server;\n    server_name a;\n  }\n  server {\n    listen *:443 quic default_server;\n    server_name b;\n  }\n}\n"},
+			want:  []string{"nginx.conf:7 default-server"},
+		},
+		{
+			name:  "server shadowed on its only socket",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    listen 80;\n  }\n  server {\n    listen 80;\n  }\n}\n"},
+			want:  []string{"nginx.conf:5 server-name-conflict", "nginx.conf:5 unreachable-server"},
+		},
+		{
+			name:  "shadowed server that is a default",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    listen 80;\n    server_name a;\n  }\n  server {\n    listen 80 default_server;\n    server_name a;\n  }\n}\n"},
+			want:  []string{"nginx.conf:6 server-name-conflict"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintFiles(t, tt.files, rules...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
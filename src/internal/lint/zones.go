@@ -0,0 +1,219 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ChrisMcKee/gofmtnginx/pkg/nginx"
+)
+
+// sizePattern matches sizes as nginx parses them: a number with an
+// optional k, m or g unit
+var sizePattern = regexp.MustCompile(`^[0-9]+[kKmMgG]?$`)
+
+// zoneDefinitions maps the directives that declare a shared memory zone
+// to the parameter holding "name:size", "" meaning the first arguments
+var zoneDefinitions = map[string]string{
+	"limit_req_zone":     "zone=",
+	"limit_conn_zone":    "zone=",
+	"proxy_cache_path":   "keys_zone=",
+	"fastcgi_cache_path": "keys_zone=",
+	"uwsgi_cache_path":   "keys_zone=",
+	"scgi_cache_path":    "keys_zone=",
+	"ssl_session_cache":  "shared:",
+	"zone":               "",
+}
+
+// zoneKinds names the kind of zone each directive declares or uses. A
+// zone can only be used by directives of the kind it was declared for.
+var zoneKinds = map[string]string{
+	"limit_req_zone":     "limit_req",
+	"limit_req":          "limit_req",
+	"limit_conn_zone":    "limit_conn",
+	"limit_conn":         "limit_conn",
+	"proxy_cache_path":   "proxy_cache",
+	"proxy_cache":        "proxy_cache",
+	"fastcgi_cache_path": "fastcgi_cache",
+	"fastcgi_cache":      "fastcgi_cache",
+	"uwsgi_cache_path":   "uwsgi_cache",
+	"uwsgi_cache":        "uwsgi_cache",
+	"scgi_cache_path":    "scgi_cache",
+	"scgi_cache":         "scgi_cache",
+	"ssl_session_cache":  "ssl_session_cache",
+	"zone":               "upstream",
+}
+
+// zone is a shared memory zone declared or used by a directive
+type zone struct {
+	directive *nginx.Directive
+	name      string
+	kind      string
+	size      string
+}
+
+// zones records the shared memory zones of a configuration. nginx keeps a
+// single namespace for them across http and stream.
+type zones struct {
+	defined map[string][]zone
+	refs    []zone
+}
+
+// declaredZone returns the zone d declares, if any
+func declaredZone(d *nginx.Directive) (zone, bool) {
+	param, ok := zoneDefinitions[d.Name]
+	if !ok {
+		return zone{}, false
+	}
+	z := zone{directive: d, kind: zoneKinds[d.Name]}
+
+	if param == "" {
+		// upstream zone name [size]
+		if len(d.Args) == 0 {
+			return zone{}, false
+		}
+		z.name = d.Args[0]
+		if len(d.Args) > 1 {
+			z.size = d.Args[1]
+		}
+		return z, true
+	}
+	for _, arg := range d.Args {
+		if value, ok := strings.CutPrefix(arg, param); ok {
+			z.name, z.size, _ = strings.Cut(value, ":")
+			return z, z.name != ""
+		}
+	}
+	return zone{}, false
+}
+
+// usedZone returns the zone d refers to, if any
+func usedZone(d *nginx.Directive) (zone, bool) {
+	z := zone{directive: d, kind: zoneKinds[d.Name]}
+	switch d.Name {
+	case "limit_req":
+		for _, arg := range d.Args {
+			if name, ok := strings.CutPrefix(arg, "zone="); ok {
+				z.name = name
+			}
+		}
+	case "limit_conn", "proxy_cache", "fastcgi_cache", "uwsgi_cache", "scgi_cache":
+		if len(d.Args) > 0 && d.Args[0] != "off" {
+			z.name = d.Args[0]
+		}
+	}
+	return z, z.name != "" && !strings.Contains(z.name, "$")
+}
+
+// analyzeZones collects the shared memory zones t declares and uses
+func analyzeZones(t *Tree) *zones {
+	z := &zones{defined: make(map[string][]zone)}
+	seen := make(map[*nginx.Directive]bool)
+	t.Walk(func(d *nginx.Directive, ctx *Context) {
+		if seen[d] {
+			return
+		}
+		seen[d] = true
+		if def, ok := declaredZone(d); ok && (d.Name != "zone" || ctx.Parent() != nil && ctx.Parent().Name == "upstream") {
+			z.defined[def.name] = append(z.defined[def.name], def)
+		}
+		if ref, ok := usedZone(d); ok {
+			z.refs = append(z.refs, ref)
+		}
+	})
+	return z
+}
+
+func (z zone) position() string {
+	return fmt.Sprintf("%s:%d", z.directive.File, z.directive.Line)
+}
+
+func init() {
+	Register(&rule{
+		id:          "zone-undefined",
+		description: "limit_req, limit_conn and cache directives using a shared memory zone that is not declared",
+		severity:    Error,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			z := analyzeZones(t)
+			var diagnostics []Diagnostic
+			for _, ref := range z.refs {
+				if len(z.defined[ref.name]) == 0 {
+					diagnostics = append(diagnostics, r.report(ref.directive, "%s uses zone %q, which is not declared", ref.directive.Name, ref.name))
+				}
+			}
+			return diagnostics
+		},
+	})
+
+	Register(&rule{
+		id:          "zone-conflict",
+		description: "shared memory zones declared more than once, or used by a directive of another kind",
+		severity:    Error,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			z := analyzeZones(t)
+			var diagnostics []Diagnostic
+			for _, defs := range z.defined {
+				first := defs[0]
+				for _, def := range defs[1:] {
+					switch {
+					case def.kind != first.kind:
+						diagnostics = append(diagnostics, r.report(def.directive, "zone %q is already declared for %s at %s", def.name, first.kind, first.position()))
+					case def.kind != "ssl_session_cache":
+						diagnostics = append(diagnostics, r.report(def.directive, "zone %q is already declared at %s", def.name, first.position()))
+					case def.size != first.size:
+						// servers share a session cache by declaring it alike
+						diagnostics = append(diagnostics, r.report(def.directive, "zone %q is declared with size %s at %s", def.name, first.size, first.position()))
+					}
+				}
+			}
+			for _, ref := range z.refs {
+				if defs := z.defined[ref.name]; len(defs) > 0 && defs[0].kind != ref.kind {
+					diagnostics = append(diagnostics, r.report(ref.directive, "%s uses zone %q, which is declared for %s at %s", ref.directive.Name, ref.name, defs[0].kind, defs[0].position()))
+				}
+			}
+			return diagnostics
+		},
+	})
+
+	Register(&rule{
+		id:          "zone-unused",
+		description: "limit_req_zone, limit_conn_zone and cache zones that nothing uses",
+		severity:    Warning,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			z := analyzeZones(t)
+			used := make(map[string]bool)
+			for _, ref := range z.refs {
+				used[ref.name] = true
+			}
+			var diagnostics []Diagnostic
+			for name, defs := range z.defined {
+				// session caches and upstream zones are used where declared
+				if kind := defs[0].kind; !used[name] && kind != "ssl_session_cache" && kind != "upstream" {
+					diagnostics = append(diagnostics, r.report(defs[0].directive, "zone %q is never used", name))
+				}
+			}
+			return diagnostics
+		},
+	})
+
+	Register(&rule{
+		id:          "zone-size",
+		description: "shared memory zone sizes that are missing or not a number with an optional k, m or g unit",
+		severity:    Warning,
+		tree: func(r *rule, t *Tree) []Diagnostic {
+			z := analyzeZones(t)
+			var diagnostics []Diagnostic
+			for _, defs := range z.defined {
+				for _, def := range defs {
+					switch {
+					case def.size == "" && def.kind != "upstream":
+						diagnostics = append(diagnostics, r.report(def.directive, "zone %q has no size", def.name))
+					case def.size != "" && !sizePattern.MatchString(def.size):
+						diagnostics = append(diagnostics, r.report(def.directive, "cannot parse size %q of zone %q", def.size, def.name))
+					}
+				}
+			}
+			return diagnostics
+		},
+	})
+}
@@ -0,0 +1,69 @@
+package lint
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestZones(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		rules []string
+		want  []string
+	}{
+		{
+			name: "zones used across includes",
+			files: map[string]string{
+				"nginx.conf": "http {\n  include zones.conf;\n  ssl_session_cache shared:SSL:10m;\n  server {\n    ssl_session_cache builtin:1000 shared:SSL:10m;\n    limit_req zone=api burst=10;\n    limit_conn addr 10;\n    location / {\n      proxy_cache pages;\n    }\n    location ~ \\.php$ {\n      fastcgi_cache php;\n    }\n  }\n  upstream app {\n    zone app 64k;\n    server 10.0.0.1;\n  }\n}\n",
+				"zones.conf": "limit_req_zone $binary_remote_addr zone=api:10m rate=10r/s;\nlimit_conn_zone $binary_remote_addr zone=addr:10m;\nproxy_cache_path /var/cache/pages keys_zone=pages:10m;\nfastcgi_cache_path /var/cache/php levels=1:2 keys_zone=php:1g;\n",
+			},
+			rules: []string{"zone-undefined", "zone-conflict", "zone-unused", "zone-size"},
+		},
+		{
+			name:  "undefined zone",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    limit_req zone=login;\n    location / {\n      proxy_cache $cache_zone;\n      fastcgi_cache off;\n    }\n  }\n}\n"},
+			rules: []string{"zone-undefined"},
+			want:  []string{"nginx.conf:3 zone-undefined"},
+		},
+		{
+			name:  "zone declared twice",
+			files: map[string]string{"nginx.conf": "http {\n  limit_req_zone $binary_remote_addr zone=one:10m rate=1r/s;\n  limit_req_zone $server_name zone=one:10m rate=5r/s;\n  limit_conn_zone $binary_remote_addr zone=one:10m;\n  server {\n    limit_req zone=one;\n  }\n}\n"},
+			rules: []string{"zone-conflict"},
+			want:  []string{"nginx.conf:3 zone-conflict", "nginx.conf:4 zone-conflict"},
+		},
+		{
+			name:  "zone used for another kind",
+			files: map[string]string{"nginx.conf": "http {\n  proxy_cache_path /var/cache keys_zone=cache:10m;\n  server {\n    fastcgi_cache cache;\n    proxy_cache cache;\n  }\n}\n"},
+			rules: []string{"zone-conflict"},
+			want:  []string{"nginx.conf:4 zone-conflict"},
+		},
+		{
+			name:  "session caches of different sizes",
+			files: map[string]string{"nginx.conf": "http {\n  server {\n    ssl_session_cache shared:SSL:10m;\n  }\n  server {\n    ssl_session_cache shared:SSL:10m;\n  }\n  server {\n    ssl_session_cache shared:SSL:20m;\n  }\n}\n"},
+			rules: []string{"zone-conflict"},
+			want:  []string{"nginx.conf:9 zone-conflict"},
+		},
+		{
+			name:  "unused zone",
+			files: map[string]string{"nginx.conf": "http {\n  limit_req_zone $binary_remote_addr zone=old:10m rate=1r/s;\n  proxy_cache_path /var/cache keys_zone=cache:10m;\n  server {\n    ssl_session_cache shared:SSL:10m;\n    proxy_cache cache;\n  }\n}\n"},
+			rules: []string{"zone-unused"},
+			want:  []string{"nginx.conf:2 zone-unused"},
+		},
+		{
+			name:  "sizes",
+			files: map[string]string{"nginx.conf": "http {\n  limit_req_zone $binary_remote_addr zone=a:10mb rate=1r/s;\n  limit_conn_zone $binary_remote_addr zone=b;\n  proxy_cache_path /var/cache keys_zone=c:1G;\n  ssl_session_cache shared:SSL:10MB;\n  upstream app {\n    zone app;\n    server 10.0.0.1;\n  }\n}\n"},
+			rules: []string{"zone-size"},
+			want:  []string{"nginx.conf:2 zone-size", "nginx.conf:3 zone-size", "nginx.conf:5 zone-size"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintFiles(t, tt.files, tt.rules...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
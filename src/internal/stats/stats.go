@@ -10,6 +10,7 @@ type Stats struct {
 	FilesProcessed int
 	FilesSkipped   int
 	FilesFailed    int
+	FilesChanged   int
 	StartTime      time.Time
 	mu             sync.Mutex
 }
@@ -38,18 +39,30 @@ func (s *Stats) IncrementFailed() {
 	s.mu.Unlock()
 }
 
+func (s *Stats) IncrementChanged() {
+	s.mu.Lock()
+	s.FilesChanged++
+	s.mu.Unlock()
+}
+
 func (s *Stats) Duration() time.Duration {
 	return time.Since(s.StartTime)
 }
 
 func (s *Stats) String() string {
+	changed := ""
+	if s.FilesChanged > 0 {
+		changed = fmt.Sprintf("📝 Files needing formatting: %d\n", s.FilesChanged)
+	}
 	return fmt.Sprintf("\nFormatting Statistics:\n"+
 		"✅ Files processed: %d\n"+
 		"💨 Files skipped: %d\n"+
 		"❌ Files failed: %d\n"+
+		"%s"+
 		"⏱️ Total time: %v\n",
 		s.FilesProcessed,
 		s.FilesSkipped,
 		s.FilesFailed,
+		changed,
 		s.Duration())
 }
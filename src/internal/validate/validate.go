@@ -0,0 +1,137 @@
+package validate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic is a single message reported by nginx -t
+type Diagnostic struct {
+	Level   string
+	Message string
+	File    string
+	Line    int
+}
+
+func (d Diagnostic) String() string {
+	if d.File == "" {
+		return fmt.Sprintf("[%s] %s", d.Level, d.Message)
+	}
+	return fmt.Sprintf("%s:%d: [%s] %s", d.File, d.Line, d.Level, d.Message)
+}
+
+// Validator runs an nginx binary against a copy of a configuration tree
+type Validator struct {
+	Binary   string
+	ConfFile string
+}
+
+func New(binary, confFile string) *Validator {
+	return &Validator{
+		Binary:   binary,
+		ConfFile: confFile,
+	}
+}
+
+var diagnosticPattern = regexp.MustCompile(`^nginx: \[(\w+)\] (.*?)(?: in (.+):(\d+))?$`)
+
+// Validate copies root into a temporary prefix, replacing the contents of
+// the files in formatted, and runs nginx -t against it. Diagnostics refer
+// to paths under root and to lines of the formatted content. The returned
+// error is non-nil if nginx could not be run or rejected the configuration.
+func (v *Validator) Validate(root string, formatted map[string][]string) ([]Diagnostic, error) {
+	prefix, err := os.MkdirTemp("", "gofmtnginx-nginx-test-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary prefix: %w", err)
+	}
+	defer os.RemoveAll(prefix)
+
+	if err := copyTree(root, prefix, formatted); err != nil {
+		return nil, err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(v.Binary, "-t", "-p", prefix+string(filepath.Separator), "-c", filepath.Join(prefix, v.ConfFile))
+	cmd.Stderr = &stderr
+	cmd.Stdout = &stderr
+	runErr := cmd.Run()
+
+	var diagnostics []Diagnostic
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		m := diagnosticPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+
+		d := Diagnostic{Level: m[1], Message: m[2]}
+		if m[3] != "" {
+			d.File = m[3]
+			d.Line, _ = strconv.Atoi(m[4])
+			if rel, err := filepath.Rel(prefix, d.File); err == nil && !strings.HasPrefix(rel, "..") {
+				d.File = filepath.Join(root, rel)
+			}
+		}
+		diagnostics = append(diagnostics, d)
+	}
+
+	if runErr != nil {
+		if _, ok := runErr.(*exec.ExitError); ok {
+			return diagnostics, fmt.Errorf("nginx rejected the configuration")
+		}
+		return diagnostics, fmt.Errorf("error running %s: %w", v.Binary, runErr)
+	}
+
+	return diagnostics, nil
+}
+
+func copyTree(root, prefix string, formatted map[string][]string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(prefix, rel)
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0o755)
+		}
+
+		if lines, ok := formatted[path]; ok {
+			return os.WriteFile(target, []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", dst, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("error copying %s: %w", src, err)
+	}
+	return nil
+}
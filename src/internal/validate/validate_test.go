@@ -0,0 +1,86 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// stubNginx reports an unknown directive for the first line containing
+// "bogus" in site.conf, mimicking the output of nginx -t
+const stubNginx = `#!/bin/sh
+while [ $# -gt 0 ]; do
+  case "$1" in
+    -p) prefix="$2"; shift ;;
+    -c) conf="$2"; shift ;;
+  esac
+  shift
+done
+line=$(grep -n bogus "${prefix}site.conf" | head -n 1 | cut -d: -f1)
+if [ -n "$line" ]; then
+  echo "nginx: [emerg] unknown directive \"bogus\" in ${prefix}site.conf:$line" >&2
+  echo "nginx: configuration file $conf test failed" >&2
+  exit 1
+fi
+echo "nginx: the configuration file $conf syntax is ok" >&2
+echo "nginx: configuration file $conf test is successful" >&2
+`
+
+func writeStub(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("stub nginx requires a POSIX shell")
+	}
+
+	stub := filepath.Join(t.TempDir(), "nginx")
+	if err := os.WriteFile(stub, []byte(stubNginx), 0o755); err != nil {
+		t.Fatalf("Failed to write stub nginx: %v", err)
+	}
+	return stub
+}
+
+func TestValidate(t *testing.T) {
+	stub := writeStub(t)
+
+	root := t.TempDir()
+	files := map[string]string{
+		"nginx.conf": "include site.conf;\n",
+		"site.conf":  "server { listen 80; }\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create test file %s: %v", name, err)
+		}
+	}
+
+	v := New(stub, "nginx.conf")
+	site := filepath.Join(root, "site.conf")
+
+	t.Run("valid", func(t *testing.T) {
+		formatted := map[string][]string{
+			site: {"server {", "  listen 80;", "}"},
+		}
+		if _, err := v.Validate(root, formatted); err != nil {
+			t.Errorf("Validate() error = %v", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		formatted := map[string][]string{
+			site: {"server {", "  listen 80;", "  bogus on;", "}"},
+		}
+		diagnostics, err := v.Validate(root, formatted)
+		if err == nil {
+			t.Fatal("Validate() expected error")
+		}
+		if len(diagnostics) != 1 {
+			t.Fatalf("Validate() got %d diagnostics, want 1: %v", len(diagnostics), diagnostics)
+		}
+
+		d := diagnostics[0]
+		if d.Level != "emerg" || d.File != site || d.Line != 3 {
+			t.Errorf("Validate() diagnostic = %s, want %s:3 at emerg level", d, site)
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package nginx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Directive is a parsed nginx directive. Block directives have a non-nil
+// Block, even when it is empty. Comments are kept as directives named "#"
+// with the text after the '#' in Comment. EndLine is the line of the
+// terminating ';' or '}'. Raw holds the source text of each argument of a
+// parsed directive so unchanged arguments are written back as they were
+// quoted.
+type Directive struct {
+	Name    string
+	Args    []string
+	Raw     []string
+	File    string
+	Line    int
+	EndLine int
+	Block   []*Directive
+	Comment string
+}
+
+// IsBlock reports whether the directive opens a block
+func (d *Directive) IsBlock() bool {
+	return d.Block != nil
+}
+
+// String returns the directive as it would be written on a single line,
+// without its block contents
+func (d *Directive) String() string {
+	if d.IsComment() {
+		return "#" + d.Comment
+	}
+
+	var b strings.Builder
+	b.WriteString(d.Name)
+	writeArgs(&b, d)
+	if d.IsBlock() {
+		b.WriteString(" {")
+	} else {
+		b.WriteString(";")
+	}
+	return b.String()
+}
+
+// IsComment reports whether the directive is a comment
+func (d *Directive) IsComment() bool {
+	return d.Name == "#"
+}
+
+// Walk calls fn for every directive in the tree in document order, passing
+// the chain of enclosing block directives
+func Walk(directives []*Directive, fn func(d *Directive, parents []*Directive)) {
+	walk(directives, nil, fn)
+}
+
+func walk(directives []*Directive, parents []*Directive, fn func(d *Directive, parents []*Directive)) {
+	for _, d := range directives {
+		fn(d, parents)
+		if d.IsBlock() {
+			walk(d.Block, append(parents[:len(parents):len(parents)], d), fn)
+		}
+	}
+}
+
+// ParseError reports a syntax error at a position in a file
+type ParseError struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
+// ParseFile parses an nginx configuration file into a directive tree
+func ParseFile(fileName string) ([]*Directive, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening input file: %w", err)
+	}
+	defer file.Close()
+
+	return parseReader(fileName, file)
+}
+
+// parseReader parses configuration read from r, recording fileName on
+// directives and errors
+func parseReader(fileName string, r io.Reader) ([]*Directive, error) {
+	tokens, err := Lex(r)
+	if err != nil {
+		if parseErr, ok := err.(*ParseError); ok {
+			parseErr.File = fileName
+			return nil, parseErr
+		}
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	return Parse(tokens, fileName)
+}
+
+// Parse builds a directive tree from tokens. file is recorded on every
+// directive for error reporting.
+func Parse(tokens []Token, file string) ([]*Directive, error) {
+	p := &parser{tokens: tokens, file: file}
+
+	directives, err := p.parseBlock(false)
+	if err != nil {
+		return nil, err
+	}
+	return directives, nil
+}
+
+type parser struct {
+	tokens []Token
+	pos    int
+	file   string
+}
+
+func (p *parser) errorf(line int, format string, args ...any) error {
+	return &ParseError{File: p.file, Line: line, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseBlock(nested bool) ([]*Directive, error) {
+	directives := []*Directive{}
+
+	for p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		p.pos++
+
+		switch tok.Kind {
+		case TokenComment:
+			directives = append(directives, &Directive{Name: "#", Args: []string{}, File: p.file, Line: tok.Line, EndLine: tok.Line, Comment: tok.Value})
+		case TokenBlockEnd:
+			if !nested {
+				return nil, p.errorf(tok.Line, "unexpected \"}\"")
+			}
+			return directives, nil
+		case TokenSemicolon, TokenBlockStart:
+			return nil, p.errorf(tok.Line, "unexpected %s", tok.Kind)
+		case TokenWord:
+			d, trailing, err := p.parseDirective(tok)
+			if err != nil {
+				return nil, err
+			}
+			directives = append(directives, d)
+			directives = append(directives, trailing...)
+		}
+	}
+
+	if nested {
+		return nil, p.errorf(p.lastLine(), "unexpected end of file, expecting \"}\"")
+	}
+	return directives, nil
+}
+
+// parseDirective reads the arguments following name up to ';' or '{'.
+// Comments that appear between the arguments are returned separately so
+// they are not lost.
+func (p *parser) parseDirective(name Token) (*Directive, []*Directive, error) {
+	d := &Directive{Name: name.Value, Args: []string{}, File: p.file, Line: name.Line}
+	var comments []*Directive
+	var words []Token
+
+	for p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		p.pos++
+
+		switch tok.Kind {
+		case TokenWord:
+			words = append(words, tok)
+			d.Args = append(d.Args, tok.Value)
+			d.Raw = append(d.Raw, tok.Raw)
+		case TokenComment:
+			comments = append(comments, &Directive{Name: "#", Args: []string{}, File: p.file, Line: tok.Line, EndLine: tok.Line, Comment: tok.Value})
+		case TokenSemicolon:
+			d.EndLine = tok.Line
+			return d, comments, nil
+		case TokenBlockStart:
+			if d.Name == "if" {
+				d.Args, d.Raw = conditionArgs(words)
+			}
+			block, err := p.parseBlock(true)
+			if err != nil {
+				return nil, nil, err
+			}
+			d.Block = block
+			d.EndLine = p.tokens[p.pos-1].Line
+			return d, comments, nil
+		case TokenBlockEnd:
+			return nil, nil, p.errorf(tok.Line, "unexpected \"}\", directive %q is not terminated by \";\"", d.Name)
+		}
+	}
+
+	return nil, nil, p.errorf(p.lastLine(), "unexpected end of file, directive %q is not terminated by \";\"", d.Name)
+}
+
+// conditionArgs returns the arguments of an if directive without the
+// parentheses around its condition, as crossplane does, so "if ($a = b)"
+// has the arguments "$a", "=" and "b". Parentheses left on their own are
+// dropped. Render puts them back. The source text of each argument is
+// returned alongside it.
+func conditionArgs(words []Token) ([]string, []string) {
+	args := make([]string, len(words))
+	raw := make([]string, len(words))
+	for i, w := range words {
+		args[i], raw[i] = w.Value, w.Raw
+	}
+	if len(words) == 0 || words[0].Quoted || words[len(words)-1].Quoted ||
+		!strings.HasPrefix(args[0], "(") || !strings.HasSuffix(args[len(args)-1], ")") {
+		return args, raw
+	}
+
+	// the words holding the parentheses are unquoted, so read as written
+	args[0] = strings.TrimLeft(args[0][1:], " \t")
+	last := len(args) - 1
+	args[last] = strings.TrimRight(args[last][:len(args[last])-1], " \t")
+	raw[0], raw[last] = args[0], args[last]
+	if args[last] == "" {
+		args, raw = args[:last], raw[:last]
+	}
+	if len(args) > 0 && args[0] == "" {
+		args, raw = args[1:], raw[1:]
+	}
+	return args, raw
+}
+
+func (p *parser) lastLine() int {
+	if len(p.tokens) == 0 {
+		return 1
+	}
+	return p.tokens[len(p.tokens)-1].Line
+}
@@ -0,0 +1,113 @@
+package nginx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func parseString(t *testing.T, input string) ([]*Directive, error) {
+	t.Helper()
+	tokens, err := Lex(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Lex() error = %v", err)
+	}
+	return Parse(tokens, "test.conf")
+}
+
+func TestParse(t *testing.T) {
+	input := `# main
+http {
+    server {
+        listen 80; # http
+        location / {}
+    }
+}`
+
+	directives, err := parseString(t, input)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(directives) != 2 || !directives[0].IsComment() || directives[0].Comment != " main" {
+		t.Fatalf("Parse() top level = %+v, want comment and http", directives)
+	}
+
+	http := directives[1]
+	if http.Name != "http" || !http.IsBlock() || http.Line != 2 {
+		t.Fatalf("Parse() http = %+v", http)
+	}
+
+	server := http.Block[0]
+	if len(server.Block) != 3 {
+		t.Fatalf("Parse() server block has %d entries, want 3", len(server.Block))
+	}
+
+	listen := server.Block[0]
+	if listen.Name != "listen" || strings.Join(listen.Args, " ") != "80" || listen.Line != 4 || listen.File != "test.conf" {
+		t.Errorf("Parse() listen = %+v", listen)
+	}
+	if !server.Block[1].IsComment() {
+		t.Errorf("Parse() expected inline comment after listen, got %+v", server.Block[1])
+	}
+
+	location := server.Block[2]
+	if !location.IsBlock() || len(location.Block) != 0 || location.Args[0] != "/" {
+		t.Errorf("Parse() location = %+v, want empty block", location)
+	}
+}
+
+func TestParseIfCondition(t *testing.T) {
+	tests := []struct {
+		input  string
+		args   []string
+		render string
+	}{
+		{`if ($request_method = POST) {}`, []string{"$request_method", "=", "POST"}, "if ($request_method = POST) {"},
+		{`if ( $slow ) {}`, []string{"$slow"}, "if ($slow) {"},
+		{`if (-f $request_filename) {}`, []string{"-f", "$request_filename"}, "if (-f $request_filename) {"},
+		{`if ($uri ~ "^/a (b)") {}`, []string{"$uri", "~", "^/a (b)"}, `if ($uri ~ "^/a (b)") {`},
+		{`if ($http_user_agent ~* "(bot|crawler)" ) {}`, []string{"$http_user_agent", "~*", "(bot|crawler)"}, `if ($http_user_agent ~* "(bot|crawler)") {`},
+	}
+
+	for _, tt := range tests {
+		directives, err := parseString(t, tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.input, err)
+		}
+		d := directives[0]
+		if strings.Join(d.Args, "|") != strings.Join(tt.args, "|") {
+			t.Errorf("Parse(%q) args = %q, want %q", tt.input, d.Args, tt.args)
+		}
+		if got := d.String(); got != tt.render {
+			t.Errorf("String() = %q, want %q", got, tt.render)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantLine int
+	}{
+		{name: "unexpected closing brace", input: "listen 80;\n}", wantLine: 2},
+		{name: "unclosed block", input: "http {\nserver {\n}", wantLine: 3},
+		{name: "missing semicolon", input: "server {\nlisten 80\n}", wantLine: 3},
+		{name: "missing semicolon at end", input: "listen 80", wantLine: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseString(t, tt.input)
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Parse() error = %v, want *ParseError", err)
+			}
+			if parseErr.Line != tt.wantLine {
+				t.Errorf("Parse() error line = %d, want %d (%v)", parseErr.Line, tt.wantLine, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package nginx
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+)
+
+// Payload is the JSON document produced by crossplane parse
+type Payload struct {
+	Status string         `json:"status" yaml:"status"`
+	Errors []PayloadError `json:"errors" yaml:"errors"`
+	Config []PayloadFile  `json:"config" yaml:"config"`
+}
+
+// PayloadError is an error in the top level errors list of a Payload
+type PayloadError struct {
+	File  string `json:"file" yaml:"file"`
+	Line  *int   `json:"line" yaml:"line"`
+	Error string `json:"error" yaml:"error"`
+}
+
+// PayloadFile is a single parsed configuration file in a Payload
+type PayloadFile struct {
+	File   string              `json:"file" yaml:"file"`
+	Status string              `json:"status" yaml:"status"`
+	Errors []PayloadFileError  `json:"errors" yaml:"errors"`
+	Parsed []*PayloadDirective `json:"parsed" yaml:"parsed"`
+}
+
+// PayloadFileError is an error attached to a single file
+type PayloadFileError struct {
+	Line  *int   `json:"line" yaml:"line"`
+	Error string `json:"error" yaml:"error"`
+}
+
+// PayloadDirective is a directive in crossplane's schema. Block is only
+// present for block directives and Includes only for include directives,
+// where it holds indexes into Payload.Config.
+type PayloadDirective struct {
+	Directive string               `json:"directive" yaml:"directive"`
+	Line      int                  `json:"line" yaml:"line"`
+	Args      []string             `json:"args" yaml:"args"`
+	Includes  []int                `json:"includes,omitempty" yaml:"includes,omitempty"`
+	Block     *[]*PayloadDirective `json:"block,omitempty" yaml:"block,omitempty"`
+	Comment   *string              `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+// Payload converts the graph into crossplane's JSON schema. Comments are
+// only included when includeComments is set.
+func (g *Graph) Payload(includeComments bool) *Payload {
+	p := &Payload{Status: "ok", Errors: []PayloadError{}, Config: []PayloadFile{}}
+
+	index := make(map[string]int)
+	for _, file := range g.Files {
+		index[file] = len(p.Config)
+		p.Config = append(p.Config, PayloadFile{File: file, Status: "ok", Errors: []PayloadFileError{}})
+	}
+
+	for _, err := range g.Errors {
+		file, line := errorPosition(err)
+		p.Status = "failed"
+		p.Errors = append(p.Errors, PayloadError{File: file, Line: line, Error: err.Error()})
+
+		if _, ok := index[file]; ok || file == "" {
+			continue
+		}
+		index[file] = len(p.Config)
+		p.Config = append(p.Config, PayloadFile{
+			File:   file,
+			Status: "failed",
+			Errors: []PayloadFileError{{Line: line, Error: err.Error()}},
+			Parsed: []*PayloadDirective{},
+		})
+	}
+
+	for _, file := range g.Files {
+		p.Config[index[file]].Parsed = g.payloadBlock(g.Configs[file], index, includeComments)
+	}
+
+	return p
+}
+
+func (g *Graph) payloadBlock(directives []*Directive, index map[string]int, includeComments bool) []*PayloadDirective {
+	parsed := []*PayloadDirective{}
+
+	for _, d := range directives {
+		if d.IsComment() {
+			if includeComments {
+				comment := d.Comment
+				parsed = append(parsed, &PayloadDirective{Directive: "#", Line: d.Line, Args: []string{}, Comment: &comment})
+			}
+			continue
+		}
+
+		pd := &PayloadDirective{Directive: d.Name, Line: d.Line, Args: append([]string{}, d.Args...)}
+		if d.Name == "include" && len(d.Args) == 1 {
+			pd.Includes = []int{}
+			for _, match := range g.resolve(d).Matches {
+				if i, ok := index[match]; ok {
+					pd.Includes = append(pd.Includes, i)
+				}
+			}
+		}
+		if d.IsBlock() {
+			block := g.payloadBlock(d.Block, index, includeComments)
+			pd.Block = &block
+		}
+		parsed = append(parsed, pd)
+	}
+
+	return parsed
+}
+
+// errorPosition extracts the file and, when known, the line of a load error
+func errorPosition(err error) (string, *int) {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		line := parseErr.Line
+		return parseErr.File, &line
+	}
+
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return filepath.Clean(pathErr.Path), nil
+	}
+	return "", nil
+}
+
+// Directives converts a parsed file back into a directive tree that can be
+// printed with Render
+func (pf *PayloadFile) Directives() []*Directive {
+	return payloadDirectives(pf.Parsed, pf.File)
+}
+
+func payloadDirectives(parsed []*PayloadDirective, file string) []*Directive {
+	directives := []*Directive{}
+	for _, pd := range parsed {
+		d := &Directive{Name: pd.Directive, Args: append([]string{}, pd.Args...), File: file, Line: pd.Line}
+		if pd.Comment != nil {
+			d.Name = "#"
+			d.Comment = *pd.Comment
+		}
+		if pd.Block != nil {
+			d.Block = payloadDirectives(*pd.Block, file)
+		}
+		directives = append(directives, d)
+	}
+	return directives
+}
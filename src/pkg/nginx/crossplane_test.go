@@ -0,0 +1,113 @@
+package nginx
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestPayload(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf":    "# main\nevents {}\nhttp {\n  include conf.d/*.conf;\n}\n",
+		"conf.d/a.conf": "server {\n  listen 80;\n}\n",
+	})
+
+	g := LoadGraph(root, filepath.Join(root, "nginx.conf"))
+
+	data, err := json.Marshal(g.Payload(false))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	expected := `{"status":"ok","errors":[],"config":[` +
+		`{"file":"ROOT/nginx.conf","status":"ok","errors":[],"parsed":[` +
+		`{"directive":"events","line":2,"args":[],"block":[]},` +
+		`{"directive":"http","line":3,"args":[],"block":[` +
+		`{"directive":"include","line":4,"args":["conf.d/*.conf"],"includes":[1]}]}]},` +
+		`{"file":"ROOT/conf.d/a.conf","status":"ok","errors":[],"parsed":[` +
+		`{"directive":"server","line":1,"args":[],"block":[` +
+		`{"directive":"listen","line":2,"args":["80"]}]}]}]}`
+	expected = strings.ReplaceAll(expected, "ROOT", root)
+
+	if string(data) != expected {
+		t.Errorf("Payload() got:\n%s\nwant:\n%s", data, expected)
+	}
+
+	data, err = json.Marshal(g.Payload(true).Config[0].Parsed[0])
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if want := `{"directive":"#","line":1,"args":[],"comment":" main"}`; string(data) != want {
+		t.Errorf("Payload() comment = %s, want %s", data, want)
+	}
+}
+
+func TestPayloadErrors(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf":  "include broken.conf;\n",
+		"broken.conf": "server {\n  listen 80\n}\n",
+	})
+
+	p := LoadGraph(root, filepath.Join(root, "nginx.conf")).Payload(false)
+	if p.Status != "failed" || len(p.Errors) != 1 {
+		t.Fatalf("Payload() status = %s with %d errors, want failed with 1", p.Status, len(p.Errors))
+	}
+
+	if p.Errors[0].File != filepath.Join(root, "broken.conf") || p.Errors[0].Line == nil || *p.Errors[0].Line != 3 {
+		t.Errorf("Payload() error = %+v, want broken.conf:3", p.Errors[0])
+	}
+
+	if len(p.Config) != 2 || p.Config[1].Status != "failed" {
+		t.Fatalf("Payload() config = %+v, want failed entry for broken.conf", p.Config)
+	}
+	if includes := p.Config[0].Parsed[0].Includes; len(includes) != 1 || includes[0] != 1 {
+		t.Errorf("Payload() include indexes = %v, want [1]", includes)
+	}
+}
+
+func TestPayloadRoundTrip(t *testing.T) {
+	// escapes are unescaped as nginx reads them and written back the same way
+	input := "# main\nhttp {\n  server {\n    listen 80; # http\n    return 200 \"a b\";\n    set $x \"a\\nb \\\" c\\\\\";\n    location / {\n    }\n  }\n}"
+	root := writeTree(t, map[string]string{"nginx.conf": input})
+
+	payload := LoadGraph(root, filepath.Join(root, "nginx.conf")).Payload(true)
+	server := (*payload.Config[0].Parsed[1].Block)[0]
+	if set := (*server.Block)[3]; set.Args[1] != "a\nb \" c\\" {
+		t.Fatalf("Payload() set value = %q, want %q", set.Args[1], "a\nb \" c\\")
+	}
+
+	formats := []struct {
+		name      string
+		marshal   func(any) ([]byte, error)
+		unmarshal func([]byte, any) error
+	}{
+		{"json", json.Marshal, json.Unmarshal},
+		{"yaml", yaml.Marshal, yaml.Unmarshal},
+	}
+
+	for _, format := range formats {
+		t.Run(format.name, func(t *testing.T) {
+			data, err := format.marshal(payload)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			decoded := &Payload{}
+			if err := format.unmarshal(data, decoded); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+
+			f := New(2, false, false)
+			rendered, err := f.Render(decoded.Config[0].Directives())
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+
+			if got := strings.Join(rendered, "\n"); got != input {
+				t.Errorf("round trip got:\n%s\nwant:\n%s", got, input)
+			}
+		})
+	}
+}
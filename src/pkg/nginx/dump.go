@@ -0,0 +1,99 @@
+package nginx
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DumpFile is a single configuration file recovered from nginx -T output
+type DumpFile struct {
+	Path  string
+	Lines []string
+}
+
+var dumpHeader = regexp.MustCompile(`^# configuration file (.+):$`)
+
+// headerPath returns the path named by a "# configuration file <path>:"
+// line, or "" if line is not a header. nginx -T starts each file on a new
+// line after a blank one, so a header elsewhere is a comment in the file
+// before it. Its path is absolute, or relative to the prefix when the
+// first file's path is.
+func headerPath(line, previous string, files []DumpFile) string {
+	m := dumpHeader.FindStringSubmatch(strings.TrimRight(line, "\r"))
+	if m == nil {
+		return ""
+	}
+	path := m[1]
+	if len(files) == 0 {
+		return path
+	}
+	if strings.TrimSpace(previous) != "" {
+		return ""
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	if filepath.IsAbs(files[0].Path) || !filepath.IsLocal(path) {
+		return ""
+	}
+	return path
+}
+
+// ParseDump splits nginx -T output into the files it contains. Anything
+// before the first "# configuration file <path>:" header, such as the
+// syntax check messages, is ignored.
+func ParseDump(r io.Reader) ([]DumpFile, error) {
+	var files []DumpFile
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	previous := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if path := headerPath(line, previous, files); path != "" {
+			files = append(files, DumpFile{Path: path})
+			previous = ""
+			continue
+		}
+		previous = line
+		if len(files) > 0 {
+			current := &files[len(files)-1]
+			current.Lines = append(current.Lines, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// nginx -T separates files with a blank line
+	for i := range files {
+		lines := files[i].Lines
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		files[i].Lines = lines
+	}
+
+	return files, nil
+}
+
+// DumpRoot returns the deepest directory containing every file in the
+// dump, so the tree can be recreated relative to it
+func DumpRoot(files []DumpFile) string {
+	if len(files) == 0 {
+		return ""
+	}
+
+	root := filepath.Dir(filepath.Clean(files[0].Path))
+	for _, file := range files[1:] {
+		path := filepath.Clean(file.Path)
+		for root != filepath.Dir(root) && !strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+			root = filepath.Dir(root)
+		}
+	}
+	return root
+}
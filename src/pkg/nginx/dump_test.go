@@ -0,0 +1,83 @@
+package nginx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDump(t *testing.T) {
+	dump := `nginx: the configuration file /etc/nginx/nginx.conf syntax is ok
+nginx: configuration file /etc/nginx/nginx.conf test is successful
+# configuration file /etc/nginx/nginx.conf:
+http {
+    include conf.d/*.conf;
+}
+
+# configuration file /etc/nginx/conf.d/default.conf:
+server {
+    # configuration file is not a header here:
+    listen 80;
+# configuration file /etc/nginx/old.conf:
+    root /srv;
+
+# configuration file old.conf:
+}
+
+`
+
+	files, err := ParseDump(strings.NewReader(dump))
+	if err != nil {
+		t.Fatalf("ParseDump() error = %v", err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("ParseDump() got %d files, want 2", len(files))
+	}
+
+	if files[0].Path != "/etc/nginx/nginx.conf" || len(files[0].Lines) != 3 {
+		t.Errorf("ParseDump() first file = %s with %d lines, want nginx.conf with 3", files[0].Path, len(files[0].Lines))
+	}
+	if files[1].Path != "/etc/nginx/conf.d/default.conf" || len(files[1].Lines) != 8 {
+		t.Errorf("ParseDump() second file = %s with %d lines, want default.conf with 8", files[1].Path, len(files[1].Lines))
+	}
+
+	if root := DumpRoot(files); root != "/etc/nginx" {
+		t.Errorf("DumpRoot() = %s, want /etc/nginx", root)
+	}
+}
+
+func TestParseDumpRelative(t *testing.T) {
+	dump := "# configuration file nginx.conf:\nhttp {\n    include conf.d/*.conf;\n}\n\n# configuration file conf.d/a.conf:\nserver {\n}\n\n"
+
+	files, err := ParseDump(strings.NewReader(dump))
+	if err != nil {
+		t.Fatalf("ParseDump() error = %v", err)
+	}
+	if len(files) != 2 || files[1].Path != "conf.d/a.conf" {
+		t.Errorf("ParseDump() = %v, want nginx.conf and conf.d/a.conf", files)
+	}
+}
+
+func TestDumpRoot(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{name: "single file", paths: []string{"/etc/nginx/nginx.conf"}, want: "/etc/nginx"},
+		{name: "sibling prefix", paths: []string{"/etc/nginx/nginx.conf", "/etc/nginx-extra/a.conf"}, want: "/etc"},
+		{name: "outside tree", paths: []string{"/etc/nginx/nginx.conf", "/usr/share/nginx/modules/a.conf"}, want: "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var files []DumpFile
+			for _, path := range tt.paths {
+				files = append(files, DumpFile{Path: path})
+			}
+			if got := DumpRoot(files); got != tt.want {
+				t.Errorf("DumpRoot() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
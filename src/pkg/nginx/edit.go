@@ -0,0 +1,482 @@
+package nginx
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Document is an editable configuration file. Edits are made to the
+// directive tree and Bytes re-emits only the lines of the directives that
+// were touched, leaving comments and formatting elsewhere as they were.
+type Document struct {
+	File       string
+	Directives []*Directive
+
+	// Formatter lays out new and changed directives. Its indentation is
+	// taken from the file where the file has nested blocks.
+	Formatter *Formatter
+
+	lines    []string
+	trailing bool
+	parents  map[*Directive]*Directive
+	changed  map[*Directive]bool
+	removed  []*Directive
+	added    []*Directive
+}
+
+// LoadDocument parses fileName for editing
+func LoadDocument(fileName string) (*Document, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening input file: %w", err)
+	}
+	return NewDocument(fileName, content)
+}
+
+// NewDocument parses content for editing. Save writes it to fileName.
+func NewDocument(fileName string, content []byte) (*Document, error) {
+	tokens, err := Lex(strings.NewReader(string(content)))
+	if err != nil {
+		if parseErr, ok := err.(*ParseError); ok {
+			parseErr.File = fileName
+		}
+		return nil, err
+	}
+	directives, err := Parse(tokens, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	text := string(content)
+	doc := &Document{
+		File:       fileName,
+		Directives: directives,
+		trailing:   strings.HasSuffix(text, "\n"),
+		parents:    make(map[*Directive]*Directive),
+		changed:    make(map[*Directive]bool),
+	}
+	doc.lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if text == "" {
+		doc.lines = nil
+	}
+	doc.Formatter = New(doc.indentUnit(2), false, false)
+
+	Walk(directives, func(d *Directive, parents []*Directive) {
+		if len(parents) > 0 {
+			doc.parents[d] = parents[len(parents)-1]
+		}
+	})
+
+	return doc, nil
+}
+
+// Find returns the directives matching a selector query in document order
+func (doc *Document) Find(query string) ([]*Directive, error) {
+	s, err := ParseSelector(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.Select(doc.Directives, nil), nil
+}
+
+// At returns the directive called name that starts on line, if any
+func (doc *Document) At(line int, name string) *Directive {
+	var found *Directive
+	Walk(doc.Directives, func(d *Directive, _ []*Directive) {
+		if found == nil && d.Line == line && d.Name == name {
+			found = d
+		}
+	})
+	return found
+}
+
+// Contains reports whether d is part of the document
+func (doc *Document) Contains(d *Directive) bool {
+	found := false
+	Walk(doc.Directives, func(n *Directive, _ []*Directive) {
+		found = found || n == d
+	})
+	return found
+}
+
+// Parent returns the block directive containing d, or nil at the top level
+func (doc *Document) Parent(d *Directive) *Directive {
+	return doc.parents[d]
+}
+
+// Modified reports whether any edits have been made
+func (doc *Document) Modified() bool {
+	return len(doc.changed) > 0 || len(doc.removed) > 0 || len(doc.added) > 0
+}
+
+// SetArgs replaces the arguments of d. Arguments d already had keep the
+// quoting they were written with.
+func (doc *Document) SetArgs(d *Directive, args ...string) {
+	raw := make([]string, len(args))
+	used := make([]bool, len(d.Args))
+	for i, arg := range args {
+		for j, old := range d.Args {
+			if !used[j] && old == arg && len(d.Raw) == len(d.Args) {
+				raw[i], used[j] = d.Raw[j], true
+				break
+			}
+		}
+	}
+	d.Args = append([]string{}, args...)
+	d.Raw = raw
+	doc.changed[d] = true
+}
+
+// Add appends a simple directive to the end of parent's block, or to the
+// end of the file when parent is nil
+func (doc *Document) Add(parent *Directive, name string, args ...string) (*Directive, error) {
+	return doc.add(parent, &Directive{Name: name, Args: append([]string{}, args...), File: doc.File})
+}
+
+// AddBlock appends an empty block directive to the end of parent's block,
+// or to the end of the file when parent is nil
+func (doc *Document) AddBlock(parent *Directive, name string, args ...string) (*Directive, error) {
+	return doc.add(parent, &Directive{Name: name, Args: append([]string{}, args...), File: doc.File, Block: []*Directive{}})
+}
+
+func (doc *Document) add(parent *Directive, d *Directive) (*Directive, error) {
+	if parent == nil {
+		doc.Directives = append(doc.Directives, d)
+	} else {
+		if !parent.IsBlock() {
+			return nil, fmt.Errorf("cannot add %q to %q, which is not a block", d.Name, parent.Name)
+		}
+		parent.Block = append(parent.Block, d)
+		doc.parents[d] = parent
+	}
+	doc.added = append(doc.added, d)
+	return d, nil
+}
+
+// Remove deletes d and its block from the document
+func (doc *Document) Remove(d *Directive) error {
+	parent := doc.parents[d]
+	siblings := &doc.Directives
+	if parent != nil {
+		siblings = &parent.Block
+	}
+
+	for i, sibling := range *siblings {
+		if sibling != d {
+			continue
+		}
+
+		// a comment on the same line goes with the directive
+		end := i + 1
+		if trailing := doc.trailingComment(*siblings, i); trailing != nil {
+			end++
+		}
+		*siblings = append((*siblings)[:i:i], (*siblings)[end:]...)
+
+		if d.Line > 0 {
+			doc.removed = append(doc.removed, d)
+		}
+		delete(doc.changed, d)
+		return nil
+	}
+
+	return fmt.Errorf("directive %q on line %d is not part of the document", d.Name, d.Line)
+}
+
+// Save writes the document back to its file, keeping the file's mode
+func (doc *Document) Save() error {
+	content, err := doc.Bytes()
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(doc.File); err == nil {
+		mode = info.Mode().Perm()
+	}
+	return os.WriteFile(doc.File, content, mode)
+}
+
+// span is a range of original lines, 1-based and inclusive, to replace
+type span struct {
+	start, end int
+	lines      []string
+}
+
+// Bytes returns the edited document. Only lines belonging to changed,
+// added or removed directives are rewritten; when a directive shares its
+// lines with others the smallest enclosing block that does not is
+// re-rendered instead.
+func (doc *Document) Bytes() ([]byte, error) {
+	if !doc.Modified() {
+		return []byte(doc.join(doc.lines)), nil
+	}
+
+	rerender := make(map[*Directive]bool)
+	whole := false
+	var spans []span
+
+	owner := func(d *Directive) {
+		for d != nil && !doc.exclusive(d) {
+			d = doc.parents[d]
+		}
+		if d == nil {
+			whole = true
+			return
+		}
+		rerender[d] = true
+	}
+
+	for d := range doc.changed {
+		if d.Line > 0 {
+			owner(d)
+		}
+	}
+
+	for _, d := range doc.removed {
+		if doc.exclusive(d) {
+			end := d.EndLine
+			if c := doc.commentAfter(d); c != nil {
+				end = c.EndLine
+			}
+			spans = append(spans, span{start: d.Line, end: end})
+			continue
+		}
+		owner(doc.parents[d])
+		if doc.parents[d] == nil {
+			whole = true
+		}
+	}
+
+	for _, d := range doc.added {
+		if doc.isAdded(doc.parents[d]) {
+			// rendered as part of its new parent
+			continue
+		}
+		parent := doc.parents[d]
+		if parent == nil {
+			lines, err := doc.render(d, "")
+			if err != nil {
+				return nil, err
+			}
+			spans = append(spans, span{start: len(doc.lines) + 1, end: len(doc.lines), lines: lines})
+			continue
+		}
+		if !doc.exclusive(parent) || strings.TrimSpace(doc.lines[parent.EndLine-1]) != "}" || parent.EndLine == parent.Line {
+			owner(parent)
+			continue
+		}
+		lines, err := doc.render(d, doc.childIndent(parent))
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, span{start: parent.EndLine, end: parent.EndLine - 1, lines: lines})
+	}
+
+	if whole {
+		lines, err := doc.Formatter.Render(doc.Directives)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", doc.File, err)
+		}
+		return []byte(doc.join(lines)), nil
+	}
+
+	for d := range rerender {
+		covered := false
+		for p := doc.parents[d]; p != nil; p = doc.parents[p] {
+			if rerender[p] {
+				covered = true
+				break
+			}
+		}
+		if covered {
+			continue
+		}
+		end := d.EndLine
+		nodes := []*Directive{d}
+		if c := doc.commentAfter(d); c != nil {
+			end = c.EndLine
+			nodes = append(nodes, c)
+		}
+		lines, err := doc.renderNodes(nodes, indentOf(doc.lines[d.Line-1]))
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, span{start: d.Line, end: end, lines: lines})
+	}
+
+	return []byte(doc.join(doc.apply(spans))), nil
+}
+
+// apply replaces spans of the original lines, skipping any span that lies
+// inside another. Insertions are spans with end == start-1.
+func (doc *Document) apply(spans []span) []string {
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+
+	var result []string
+	next := 1
+	lastEnd := 0
+	for _, s := range spans {
+		if s.end >= s.start && s.end <= lastEnd {
+			continue
+		}
+		if s.start < next {
+			if s.end < s.start && s.start > lastEnd {
+				result = append(result, s.lines...)
+			}
+			continue
+		}
+		result = append(result, doc.lines[next-1:s.start-1]...)
+		result = append(result, s.lines...)
+		next = s.end + 1
+		if s.end >= s.start {
+			lastEnd = s.end
+		}
+	}
+	return append(result, doc.lines[min(next-1, len(doc.lines)):]...)
+}
+
+// exclusive reports whether d, with any comment on its last line, is the
+// only thing on its lines so they can be rewritten without touching other
+// directives
+func (doc *Document) exclusive(d *Directive) bool {
+	if d.Line == 0 || d.EndLine == 0 {
+		return false
+	}
+
+	trailing := doc.commentAfter(d)
+	inside := func(n *Directive) bool {
+		for p := doc.parents[n]; p != nil; p = doc.parents[p] {
+			if p == d {
+				return true
+			}
+		}
+		return false
+	}
+
+	isolated := true
+	check := func(n *Directive) {
+		if n == d || n == trailing || n.Line == 0 || inside(n) {
+			return
+		}
+		if n.Line <= d.EndLine && n.EndLine >= d.Line {
+			// ancestors may enclose d as long as their braces are elsewhere
+			if n.IsBlock() && n.Line < d.Line && n.EndLine > d.EndLine {
+				return
+			}
+			isolated = false
+		}
+	}
+	Walk(doc.Directives, func(n *Directive, _ []*Directive) { check(n) })
+	for _, n := range doc.removed {
+		check(n)
+	}
+	return isolated
+}
+
+// commentAfter returns the comment written on the same line after d
+func (doc *Document) commentAfter(d *Directive) *Directive {
+	var found *Directive
+	Walk(doc.Directives, func(n *Directive, _ []*Directive) {
+		if found == nil && n.IsComment() && n != d && d.EndLine > 0 && n.Line == d.EndLine && doc.parents[n] == doc.parents[d] && !d.IsBlock() {
+			found = n
+		}
+	})
+	return found
+}
+
+func (doc *Document) trailingComment(siblings []*Directive, i int) *Directive {
+	if i+1 < len(siblings) {
+		next := siblings[i+1]
+		d := siblings[i]
+		if next.IsComment() && !d.IsBlock() && d.EndLine > 0 && next.Line == d.EndLine {
+			return next
+		}
+	}
+	return nil
+}
+
+func (doc *Document) isAdded(d *Directive) bool {
+	for _, a := range doc.added {
+		if a == d {
+			return true
+		}
+	}
+	return false
+}
+
+// childIndent returns the indentation for a new child of parent, copied
+// from its existing children where possible
+func (doc *Document) childIndent(parent *Directive) string {
+	for _, child := range parent.Block {
+		if child.Line > 0 && child.Line != parent.Line {
+			return indentOf(doc.lines[child.Line-1])
+		}
+	}
+	return indentOf(doc.lines[parent.Line-1]) + strings.Repeat(" ", doc.Formatter.IndentSize)
+}
+
+func (doc *Document) render(d *Directive, indent string) ([]string, error) {
+	return doc.renderNodes([]*Directive{d}, indent)
+}
+
+func (doc *Document) renderNodes(nodes []*Directive, indent string) ([]string, error) {
+	rendered, err := doc.Formatter.Render(nodes)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%d: %w", doc.File, nodes[0].Line, err)
+	}
+	for i, line := range rendered {
+		if line != "" {
+			rendered[i] = indent + line
+		}
+	}
+	return rendered, nil
+}
+
+// indentUnit returns the number of spaces the file indents a block's
+// children by, the most common step between a block and its first child,
+// or fallback if the file has no such step indented with spaces
+func (doc *Document) indentUnit(fallback int) int {
+	steps := make(map[int]int)
+	Walk(doc.Directives, func(d *Directive, _ []*Directive) {
+		if !d.IsBlock() || d.Line == 0 {
+			return
+		}
+		for _, child := range d.Block {
+			if child.Line == d.Line {
+				continue
+			}
+			outer, inner := indentOf(doc.lines[d.Line-1]), indentOf(doc.lines[child.Line-1])
+			if !strings.Contains(outer+inner, "\t") && len(inner) > len(outer) {
+				steps[len(inner)-len(outer)]++
+			}
+			break
+		}
+	})
+
+	unit, count := fallback, 0
+	for step, n := range steps {
+		if n > count || n == count && step < unit {
+			unit, count = step, n
+		}
+	}
+	return unit
+}
+
+func (doc *Document) join(lines []string) string {
+	text := strings.Join(lines, "\n")
+	if len(lines) > 0 && (doc.trailing || doc.Modified()) {
+		text += "\n"
+	}
+	return text
+}
+
+func indentOf(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
@@ -0,0 +1,280 @@
+package nginx
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadTestDocument(t *testing.T, content string) *Document {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.conf")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	doc, err := LoadDocument(path)
+	if err != nil {
+		t.Fatalf("LoadDocument() error = %v", err)
+	}
+	return doc
+}
+
+func findOne(t *testing.T, doc *Document, query string) *Directive {
+	t.Helper()
+	matches, err := doc.Find(query)
+	if err != nil {
+		t.Fatalf("Find(%q) error = %v", query, err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("Find(%q) got %d matches, want 1", query, len(matches))
+	}
+	return matches[0]
+}
+
+const editInput = `# main site
+server {
+	listen 80;   # http
+	server_name example.com;
+
+	# health checks
+	location /health { return 200; }
+
+	location / {
+	    root   /var/www;
+	}
+}
+`
+
+func TestDocumentEdit(t *testing.T) {
+	tests := []struct {
+		name     string
+		edit     func(t *testing.T, doc *Document)
+		expected string
+	}{
+		{
+			name:     "no changes",
+			edit:     func(t *testing.T, doc *Document) {},
+			expected: editInput,
+		},
+		{
+			name: "set args",
+			edit: func(t *testing.T, doc *Document) {
+				doc.SetArgs(findOne(t, doc, "server_name"), "example.org", "www.example.org")
+			},
+			expected: `# main site
+server {
+	listen 80;   # http
+	server_name example.org www.example.org;
+
+	# health checks
+	location /health { return 200; }
+
+	location / {
+	    root   /var/www;
+	}
+}
+`,
+		},
+		{
+			name: "set args keeps trailing comment",
+			edit: func(t *testing.T, doc *Document) {
+				doc.SetArgs(findOne(t, doc, "listen"), "443", "ssl")
+			},
+			expected: `# main site
+server {
+	listen 443 ssl; # http
+	server_name example.com;
+
+	# health checks
+	location /health { return 200; }
+
+	location / {
+	    root   /var/www;
+	}
+}
+`,
+		},
+		{
+			name: "set args on shared line re-renders enclosing block",
+			edit: func(t *testing.T, doc *Document) {
+				doc.SetArgs(findOne(t, doc, "return"), "204")
+			},
+			expected: `# main site
+server {
+	listen 80;   # http
+	server_name example.com;
+
+	# health checks
+	location /health {
+	  return 204;
+	}
+
+	location / {
+	    root   /var/www;
+	}
+}
+`,
+		},
+		{
+			name: "remove directive and its comment",
+			edit: func(t *testing.T, doc *Document) {
+				if err := doc.Remove(findOne(t, doc, "listen")); err != nil {
+					t.Fatalf("Remove() error = %v", err)
+				}
+			},
+			expected: `# main site
+server {
+	server_name example.com;
+
+	# health checks
+	location /health { return 200; }
+
+	location / {
+	    root   /var/www;
+	}
+}
+`,
+		},
+		{
+			name: "remove block",
+			edit: func(t *testing.T, doc *Document) {
+				if err := doc.Remove(findOne(t, doc, "location[args=/]")); err != nil {
+					t.Fatalf("Remove() error = %v", err)
+				}
+			},
+			expected: `# main site
+server {
+	listen 80;   # http
+	server_name example.com;
+
+	# health checks
+	location /health { return 200; }
+
+}
+`,
+		},
+		{
+			name: "add directive and block",
+			edit: func(t *testing.T, doc *Document) {
+				server := findOne(t, doc, "server")
+				if _, err := doc.Add(server, "client_max_body_size", "50m"); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+				location, err := doc.AddBlock(server, "location", "/api/")
+				if err != nil {
+					t.Fatalf("AddBlock() error = %v", err)
+				}
+				if _, err := doc.Add(location, "proxy_pass", "http://backend"); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+				if _, err := doc.Add(nil, "map", "$a", "$b"); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+			},
+			expected: `# main site
+server {
+	listen 80;   # http
+	server_name example.com;
+
+	# health checks
+	location /health { return 200; }
+
+	location / {
+	    root   /var/www;
+	}
+	client_max_body_size 50m;
+	location /api/ {
+	  proxy_pass http://backend;
+	}
+}
+map $a $b;
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := loadTestDocument(t, editInput)
+			tt.edit(t, doc)
+
+			got, err := doc.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Bytes() got:\n%s\nwant:\n%s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDocumentSave(t *testing.T) {
+	doc := loadTestDocument(t, "events {}\nhttp { gzip on; }\n")
+	doc.SetArgs(findOne(t, doc, "gzip"), "off")
+
+	if err := doc.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	content, err := os.ReadFile(doc.File)
+	if err != nil {
+		t.Fatalf("Failed to read saved file: %v", err)
+	}
+	if want := "events {}\nhttp {\n  gzip off;\n}\n"; string(content) != want {
+		t.Errorf("Save() wrote:\n%s\nwant:\n%s", content, want)
+	}
+}
+
+func TestDocumentSaveKeepsMode(t *testing.T) {
+	doc := loadTestDocument(t, "http {\n  gzip on;\n}\n")
+	if err := os.Chmod(doc.File, 0o600); err != nil {
+		t.Fatalf("Failed to chmod test file: %v", err)
+	}
+	doc.SetArgs(findOne(t, doc, "gzip"), "off")
+
+	if err := doc.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	info, err := os.Stat(doc.File)
+	if err != nil {
+		t.Fatalf("Failed to stat saved file: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("Save() mode = %v, want %v", mode, os.FileMode(0o600))
+	}
+}
+
+func TestDocumentIndentUnit(t *testing.T) {
+	doc := loadTestDocument(t, "http {\n    server { listen 80; }\n}\n")
+	doc.SetArgs(findOne(t, doc, "listen"), "8080")
+
+	got, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	if want := "http {\n    server {\n        listen 8080;\n    }\n}\n"; string(got) != want {
+		t.Errorf("Bytes() got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDocumentRenderError(t *testing.T) {
+	doc := loadTestDocument(t, "http { gzip on; }\n")
+	// longer than a line the formatter can scan
+	doc.SetArgs(findOne(t, doc, "gzip"), strings.Repeat("x", 70000))
+
+	if _, err := doc.Bytes(); err == nil {
+		t.Error("Bytes() error = nil, want render error")
+	}
+	if err := doc.Save(); err == nil {
+		t.Error("Save() error = nil, want render error")
+	}
+	content, err := os.ReadFile(doc.File)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	if string(content) != "http { gzip on; }\n" {
+		t.Errorf("Save() overwrote the file after a render error:\n%s", content)
+	}
+}
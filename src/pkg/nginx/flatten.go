@@ -0,0 +1,59 @@
+package nginx
+
+import (
+	"path/filepath"
+)
+
+// Flatten returns the directives of entry with every include replaced by
+// the directives of the files it matches. Each inlined file is preceded by
+// a "# configuration file <path>:" comment, as printed by nginx -T. Includes
+// that would re-enter a file already being inlined, or that match a file
+// which failed to load, are left in place.
+func (g *Graph) Flatten(entry string) []*Directive {
+	entry = filepath.Clean(entry)
+	return g.inline(entry, map[string]bool{})
+}
+
+// FileHeader returns the comment nginx -T prints before each file
+func FileHeader(fileName string) string {
+	return " configuration file " + fileName + ":"
+}
+
+func (g *Graph) inline(fileName string, stack map[string]bool) []*Directive {
+	stack[fileName] = true
+	defer delete(stack, fileName)
+
+	header := &Directive{Name: "#", Args: []string{}, File: fileName, Comment: FileHeader(fileName)}
+	return append([]*Directive{header}, g.inlineBlock(g.Configs[fileName], stack)...)
+}
+
+func (g *Graph) inlineBlock(directives []*Directive, stack map[string]bool) []*Directive {
+	result := []*Directive{}
+
+	for _, d := range directives {
+		if d.Name == "include" && len(d.Args) == 1 {
+			inc := g.resolve(d)
+			var inlined []*Directive
+			keep := false
+			for _, match := range inc.Matches {
+				if _, ok := g.Configs[match]; !ok || stack[match] {
+					keep = true
+					break
+				}
+				inlined = append(inlined, g.inline(match, stack)...)
+			}
+			if !keep {
+				result = append(result, inlined...)
+				continue
+			}
+		}
+
+		copied := *d
+		if d.IsBlock() {
+			copied.Block = g.inlineBlock(d.Block, stack)
+		}
+		result = append(result, &copied)
+	}
+
+	return result
+}
@@ -0,0 +1,72 @@
+package nginx
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	input := `http { server { listen 80; # http
+return 200 "a b"; set $x "${host}x"; location / {} } }`
+
+	directives, err := parseString(t, input)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	f := New(2, false, false)
+	rendered, err := f.Render(directives)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	expected := []string{
+		"http {",
+		"  server {",
+		"    listen 80; # http",
+		`    return 200 "a b";`,
+		`    set $x "${host}x";`,
+		"    location / {",
+		"    }",
+		"  }",
+		"}",
+	}
+	if strings.Join(rendered, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Render() got:\n%s\nwant:\n%s", formatLines(rendered), formatLines(expected))
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf":         "http {\ninclude conf.d/*.conf;\n}\n",
+		"conf.d/a.conf":      "server {\ninclude snippets/ssl;\n}\n",
+		"conf.d/b.conf":      "include conf.d/b.conf;\n",
+		"snippets/ssl":       "ssl_protocols TLSv1.3;\n",
+		"snippets/unrelated": "gzip on;\n",
+	})
+	entry := filepath.Join(root, "nginx.conf")
+
+	g := LoadGraph(root, entry)
+	f := New(2, false, false)
+	flattened, err := f.Render(g.Flatten(entry))
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	expected := []string{
+		"#" + FileHeader(entry),
+		"http {",
+		"  #" + FileHeader(filepath.Join(root, "conf.d/a.conf")),
+		"  server {",
+		"    #" + FileHeader(filepath.Join(root, "snippets/ssl")),
+		"    ssl_protocols TLSv1.3;",
+		"  }",
+		"  #" + FileHeader(filepath.Join(root, "conf.d/b.conf")),
+		"  include conf.d/b.conf;",
+		"}",
+	}
+	if strings.Join(flattened, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Flatten() got:\n%s\nwant:\n%s", formatLines(flattened), formatLines(expected))
+	}
+}
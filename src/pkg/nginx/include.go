@@ -0,0 +1,306 @@
+package nginx
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Include is a single include directive and the files it resolved to
+type Include struct {
+	File    string
+	Line    int
+	Pattern string
+	Matches []string
+}
+
+// Graph is the set of configuration files reachable from one or more entry
+// points by following include directives
+type Graph struct {
+	Prefix   string
+	Entries  []string
+	Files    []string
+	Configs  map[string][]*Directive
+	Includes map[string][]Include
+	Errors   []error
+
+	// parents records the include that first reached each file
+	parents map[string]Include
+	// failed records the files that could not be loaded, so each is
+	// reported once however often it is included
+	failed  map[string]bool
+	onStack map[string]bool
+	cycles  []IncludeDiagnostic
+	overlay map[string][]byte
+}
+
+// LoadGraph parses every entry point and every file they include. Relative
+// include paths are resolved against prefix, as nginx resolves them against
+// the directory given with -p. Files that cannot be read or parsed are
+// recorded in Errors and are not followed further.
+func LoadGraph(prefix string, entries ...string) *Graph {
+	return LoadGraphOverlay(prefix, nil, entries...)
+}
+
+// LoadGraphOverlay is like LoadGraph but reads the files in overlay, keyed
+// by cleaned path, from there instead of from disk. This lets edited
+// content be loaded before it is written.
+func LoadGraphOverlay(prefix string, overlay map[string][]byte, entries ...string) *Graph {
+	g := &Graph{
+		Prefix:   prefix,
+		Entries:  entries,
+		Configs:  make(map[string][]*Directive),
+		Includes: make(map[string][]Include),
+		parents:  make(map[string]Include),
+		failed:   make(map[string]bool),
+		onStack:  make(map[string]bool),
+		overlay:  overlay,
+	}
+
+	for _, entry := range entries {
+		g.load(filepath.Clean(entry))
+	}
+
+	return g
+}
+
+// Resolve returns the path an include pattern refers to
+func (g *Graph) Resolve(pattern string) string {
+	if filepath.IsAbs(pattern) {
+		return filepath.Clean(pattern)
+	}
+	return filepath.Join(g.Prefix, pattern)
+}
+
+// ReadFile returns the content the graph was loaded from for a file,
+// taking the overlay into account
+func (g *Graph) ReadFile(fileName string) ([]byte, error) {
+	if content, ok := g.overlay[fileName]; ok {
+		return content, nil
+	}
+	return os.ReadFile(fileName)
+}
+
+func (g *Graph) load(fileName string) {
+	if _, ok := g.Configs[fileName]; ok || g.failed[fileName] {
+		return
+	}
+
+	var directives []*Directive
+	var err error
+	if content, ok := g.overlay[fileName]; ok {
+		directives, err = parseReader(fileName, bytes.NewReader(content))
+	} else {
+		directives, err = ParseFile(fileName)
+	}
+	if err != nil {
+		g.Errors = append(g.Errors, err)
+		g.failed[fileName] = true
+		return
+	}
+
+	g.Configs[fileName] = directives
+	g.Files = append(g.Files, fileName)
+	g.onStack[fileName] = true
+	defer delete(g.onStack, fileName)
+
+	var includes []Include
+	Walk(directives, func(d *Directive, _ []*Directive) {
+		if d.Name != "include" || len(d.Args) != 1 {
+			return
+		}
+		includes = append(includes, g.resolve(d))
+	})
+	g.Includes[fileName] = includes
+
+	for _, inc := range includes {
+		for _, match := range inc.Matches {
+			if g.onStack[match] {
+				g.cycles = append(g.cycles, IncludeDiagnostic{
+					Kind:    IncludeCycle,
+					Include: inc,
+					Target:  match,
+					Chain:   g.chain(inc.File),
+				})
+				continue
+			}
+			if _, ok := g.Configs[match]; !ok && !g.failed[match] {
+				g.parents[match] = inc
+			}
+			g.load(match)
+		}
+	}
+}
+
+func (g *Graph) resolve(d *Directive) Include {
+	inc := Include{File: d.File, Line: d.Line, Pattern: d.Args[0]}
+	path := g.Resolve(inc.Pattern)
+
+	if strings.ContainsAny(inc.Pattern, "*?[") {
+		matches, _ := filepath.Glob(path)
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() {
+				inc.Matches = append(inc.Matches, match)
+			}
+		}
+		return inc
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		inc.Matches = []string{path}
+	}
+	return inc
+}
+
+// IncludeProblem identifies the kind of an include diagnostic
+type IncludeProblem int
+
+const (
+	IncludeCycle IncludeProblem = iota
+	IncludeMissing
+	IncludeOutsideRoot
+)
+
+func (p IncludeProblem) String() string {
+	switch p {
+	case IncludeCycle:
+		return "include cycle"
+	case IncludeMissing:
+		return "include matches no files"
+	case IncludeOutsideRoot:
+		return "include outside root"
+	}
+	return "unknown include problem"
+}
+
+// IncludeDiagnostic describes a problem with an include directive along
+// with the chain of includes that led from an entry point to it
+type IncludeDiagnostic struct {
+	Kind    IncludeProblem
+	Include Include
+	Target  string
+	Chain   []Include
+}
+
+func (d IncludeDiagnostic) String() string {
+	msg := fmt.Sprintf("%s:%d: %s: %s", d.Include.File, d.Include.Line, d.Kind, d.Target)
+	if len(d.Chain) == 0 {
+		return msg
+	}
+
+	links := make([]string, 0, len(d.Chain))
+	for _, inc := range d.Chain {
+		links = append(links, fmt.Sprintf("%s:%d", inc.File, inc.Line))
+	}
+	return msg + " (included via " + strings.Join(links, " -> ") + ")"
+}
+
+// Diagnostics reports include cycles, includes that match no files and,
+// when root is not empty, includes that resolve to files outside root
+func (g *Graph) Diagnostics(root string) []IncludeDiagnostic {
+	diagnostics := append([]IncludeDiagnostic(nil), g.cycles...)
+
+	absRoot, _ := filepath.Abs(root)
+	for _, file := range g.Files {
+		for _, inc := range g.Includes[file] {
+			if len(inc.Matches) == 0 {
+				diagnostics = append(diagnostics, IncludeDiagnostic{
+					Kind:    IncludeMissing,
+					Include: inc,
+					Target:  g.Resolve(inc.Pattern),
+					Chain:   g.chain(inc.File),
+				})
+				continue
+			}
+			if root == "" {
+				continue
+			}
+			for _, match := range inc.Matches {
+				if !within(absRoot, match) {
+					diagnostics = append(diagnostics, IncludeDiagnostic{
+						Kind:    IncludeOutsideRoot,
+						Include: inc,
+						Target:  match,
+						Chain:   g.chain(inc.File),
+					})
+				}
+			}
+		}
+	}
+
+	return diagnostics
+}
+
+// chain returns the includes leading from an entry point to fileName
+func (g *Graph) chain(fileName string) []Include {
+	var chain []Include
+	seen := make(map[string]bool)
+	for !seen[fileName] {
+		seen[fileName] = true
+		inc, ok := g.parents[fileName]
+		if !ok {
+			break
+		}
+		chain = append([]Include{inc}, chain...)
+		fileName = inc.File
+	}
+	return chain
+}
+
+// within reports whether path is root or inside it
+func within(root, path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, abs)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Expand replaces include directives in a block with the top level
+// directives of the files they match, recursively, so a block can be
+// searched as nginx sees it. Includes that would re-enter a file being
+// expanded, or whose files failed to load, are kept as they are.
+func (g *Graph) Expand(directives []*Directive) []*Directive {
+	return g.expand(directives, map[string]bool{})
+}
+
+func (g *Graph) expand(directives []*Directive, stack map[string]bool) []*Directive {
+	var result []*Directive
+	for _, d := range directives {
+		if d.Name != "include" || len(d.Args) != 1 {
+			result = append(result, d)
+			continue
+		}
+
+		inc := g.resolve(d)
+		var expanded []*Directive
+		keep := false
+		for _, match := range inc.Matches {
+			config, ok := g.Configs[match]
+			if !ok || stack[match] {
+				keep = true
+				break
+			}
+			stack[match] = true
+			expanded = append(expanded, g.expand(config, stack)...)
+			delete(stack, match)
+		}
+
+		if keep {
+			result = append(result, d)
+		} else {
+			result = append(result, expanded...)
+		}
+	}
+	return result
+}
+
+// Query returns the directives matching s across every file reachable
+// from entry
+func (g *Graph) Query(s *Selector, entry string) []*Directive {
+	entry = filepath.Clean(entry)
+	return s.Select(g.expand(g.Configs[entry], map[string]bool{entry: true}), g.Expand)
+}
@@ -0,0 +1,116 @@
+package nginx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTree creates files under a temporary directory and returns its path
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create test file %s: %v", name, err)
+		}
+	}
+	return root
+}
+
+func TestLoadGraph(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf":                "http {\n  include conf.d/*.conf;\n  include sites-enabled/*;\n}\n",
+		"conf.d/a.conf":             "gzip on;\n",
+		"conf.d/b.conf":             "include snippets/common;\n",
+		"sites-enabled/example":     "server {\n  include snippets/common;\n}\n",
+		"snippets/common":           "add_header X-Test 1;\n",
+		"sites-available/unused":    "server {}\n",
+		"conf.d/ignored.conf.other": "x;\n",
+	})
+
+	g := LoadGraph(root, filepath.Join(root, "nginx.conf"))
+	if len(g.Errors) != 0 {
+		t.Fatalf("LoadGraph() errors = %v", g.Errors)
+	}
+
+	want := []string{
+		"nginx.conf",
+		"conf.d/a.conf",
+		"conf.d/b.conf",
+		"snippets/common",
+		"sites-enabled/example",
+	}
+	if len(g.Files) != len(want) {
+		t.Fatalf("LoadGraph() files = %v, want %v", g.Files, want)
+	}
+	for i, name := range want {
+		if g.Files[i] != filepath.Join(root, name) {
+			t.Errorf("LoadGraph() file %d = %s, want %s", i, g.Files[i], name)
+		}
+	}
+
+	includes := g.Includes[filepath.Join(root, "nginx.conf")]
+	if len(includes) != 2 || includes[0].Line != 2 || len(includes[0].Matches) != 2 {
+		t.Errorf("LoadGraph() includes of nginx.conf = %+v", includes)
+	}
+}
+
+func TestLoadGraphParseError(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf":  "include broken.conf;\nhttp {\n  include broken.conf;\n  server {\n    include broken.conf;\n  }\n}\n",
+		"broken.conf": "server {\n",
+	})
+
+	g := LoadGraph(root, filepath.Join(root, "nginx.conf"))
+	if len(g.Errors) != 1 {
+		t.Errorf("LoadGraph() errors = %v, want 1", g.Errors)
+	}
+	if len(g.Files) != 1 {
+		t.Errorf("LoadGraph() files = %v, want only nginx.conf", g.Files)
+	}
+}
+
+func TestGraphDiagnostics(t *testing.T) {
+	outside := writeTree(t, map[string]string{
+		"shared.conf": "gzip on;\n",
+	})
+	root := writeTree(t, map[string]string{
+		"nginx.conf":    "include conf.d/*.conf;\ninclude missing/*.conf;\n",
+		"conf.d/a.conf": "include loop.inc;\ninclude " + filepath.Join(outside, "shared.conf") + ";\n",
+		"loop.inc":      "include conf.d/a.conf;\n",
+	})
+
+	g := LoadGraph(root, filepath.Join(root, "nginx.conf"))
+	diagnostics := g.Diagnostics(root)
+
+	byKind := make(map[IncludeProblem]IncludeDiagnostic)
+	for _, d := range diagnostics {
+		byKind[d.Kind] = d
+	}
+	if len(diagnostics) != 3 || len(byKind) != 3 {
+		t.Fatalf("Diagnostics() = %v, want one cycle, one missing and one outside root", diagnostics)
+	}
+
+	cycle := byKind[IncludeCycle]
+	if cycle.Include.File != filepath.Join(root, "loop.inc") || cycle.Target != filepath.Join(root, "conf.d/a.conf") {
+		t.Errorf("cycle diagnostic = %s", cycle)
+	}
+	if len(cycle.Chain) != 2 || cycle.Chain[0].File != filepath.Join(root, "nginx.conf") || cycle.Chain[1].Line != 1 {
+		t.Errorf("cycle chain = %+v, want nginx.conf:1 -> conf.d/a.conf:1", cycle.Chain)
+	}
+
+	missing := byKind[IncludeMissing]
+	if missing.Include.Line != 2 || len(missing.Chain) != 0 {
+		t.Errorf("missing diagnostic = %s", missing)
+	}
+
+	outsideRoot := byKind[IncludeOutsideRoot]
+	if outsideRoot.Include.Line != 2 || len(outsideRoot.Chain) != 1 {
+		t.Errorf("outside root diagnostic = %s", outsideRoot)
+	}
+}
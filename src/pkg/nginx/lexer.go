@@ -0,0 +1,225 @@
+package nginx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TokenKind identifies the type of a lexical token
+type TokenKind int
+
+const (
+	TokenWord TokenKind = iota
+	TokenSemicolon
+	TokenBlockStart
+	TokenBlockEnd
+	TokenComment
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenWord:
+		return "word"
+	case TokenSemicolon:
+		return "';'"
+	case TokenBlockStart:
+		return "'{'"
+	case TokenBlockEnd:
+		return "'}'"
+	case TokenComment:
+		return "comment"
+	}
+	return "unknown"
+}
+
+// Token is a single lexical element of an nginx configuration as nginx
+// itself reads it. Raw is the source text of a word, quotes and escapes
+// included.
+type Token struct {
+	Kind   TokenKind
+	Value  string
+	Raw    string
+	Quoted bool
+	Line   int
+	Column int
+}
+
+// Pos returns the token position as line:column
+func (t Token) Pos() string {
+	return fmt.Sprintf("%d:%d", t.Line, t.Column)
+}
+
+func (t Token) String() string {
+	switch t.Kind {
+	case TokenWord:
+		if t.Quoted {
+			return fmt.Sprintf("%q", t.Value)
+		}
+		return t.Value
+	case TokenComment:
+		return "#" + t.Value
+	}
+	return strings.Trim(t.Kind.String(), "'")
+}
+
+// unescape returns what nginx reads for a backslash followed by next:
+// the character itself for a quote or backslash, a tab, carriage return
+// or newline for \t, \r and \n, and both characters for anything else
+func unescape(next rune) string {
+	switch next {
+	case '"', '\'', '\\':
+		return string(next)
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	case 'n':
+		return "\n"
+	}
+	return "\\" + string(next)
+}
+
+// Lex splits nginx configuration read from r into tokens following the
+// rules of ngx_conf_read_token: quotes and backslashes protect special
+// characters, '#' only starts a comment at the beginning of a token and
+// '}' only closes a block at the beginning of a token. Token values are
+// unescaped as nginx does, in quoted and unquoted words alike.
+func Lex(r io.Reader) ([]Token, error) {
+	reader := bufio.NewReader(r)
+
+	var (
+		tokens []Token
+		word   strings.Builder
+		raw    strings.Builder
+		inWord bool
+		start  Token
+		quote  rune
+	)
+
+	line, col := 1, 0
+	var last rune
+
+	flush := func() {
+		if inWord {
+			start.Value = word.String()
+			start.Raw = raw.String()
+			tokens = append(tokens, start)
+			word.Reset()
+			raw.Reset()
+			inWord = false
+		}
+	}
+
+	for {
+		r, _, err := reader.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		col++
+		if r == '\n' {
+			line++
+			col = 0
+		}
+
+		if quote != 0 {
+			switch {
+			case r == '\\':
+				next, _, err := reader.ReadRune()
+				if err != nil {
+					return nil, &ParseError{Line: start.Line, Message: fmt.Sprintf("unexpected end of file in quoted string starting at %s", start.Pos())}
+				}
+				col++
+				if next == '\n' {
+					line++
+					col = 0
+				}
+				word.WriteString(unescape(next))
+				raw.WriteRune(r)
+				raw.WriteRune(next)
+			case r == quote:
+				raw.WriteRune(r)
+				quote = 0
+				flush()
+			default:
+				word.WriteRune(r)
+				raw.WriteRune(r)
+			}
+			last = r
+			continue
+		}
+
+		switch {
+		case r == ' ' || r == '\t' || r == '\r' || r == '\n':
+			flush()
+		case r == '#' && !inWord:
+			comment, _ := reader.ReadString('\n')
+			tokens = append(tokens, Token{Kind: TokenComment, Value: strings.TrimRight(comment, "\r\n"), Line: line, Column: col})
+			if strings.HasSuffix(comment, "\n") {
+				line++
+				col = 0
+			}
+		case (r == '"' || r == '\'') && !inWord:
+			quote = r
+			inWord = true
+			start = Token{Kind: TokenWord, Quoted: true, Line: line, Column: col}
+			raw.WriteRune(r)
+		case r == '{' && inWord && last == '$':
+			// ${var} is a variable reference, not a block
+			word.WriteRune(r)
+			raw.WriteRune(r)
+		case r == '}' && inWord:
+			// like nginx, only whitespace, ';' and '{' end an unquoted word
+			word.WriteRune(r)
+			raw.WriteRune(r)
+		case r == ';' || r == '{' || r == '}':
+			flush()
+			kind := TokenSemicolon
+			if r == '{' {
+				kind = TokenBlockStart
+			} else if r == '}' {
+				kind = TokenBlockEnd
+			}
+			tokens = append(tokens, Token{Kind: kind, Value: string(r), Line: line, Column: col})
+		case r == '\\':
+			if !inWord {
+				inWord = true
+				start = Token{Kind: TokenWord, Line: line, Column: col}
+			}
+			raw.WriteRune(r)
+			next, _, err := reader.ReadRune()
+			if err != nil {
+				word.WriteRune(r)
+				break
+			}
+			col++
+			if next == '\n' {
+				line++
+				col = 0
+			}
+			word.WriteString(unescape(next))
+			raw.WriteRune(next)
+			r = next
+		default:
+			if !inWord {
+				inWord = true
+				start = Token{Kind: TokenWord, Line: line, Column: col}
+			}
+			word.WriteRune(r)
+			raw.WriteRune(r)
+		}
+		last = r
+	}
+
+	if quote != 0 {
+		return nil, &ParseError{Line: start.Line, Message: fmt.Sprintf("unexpected end of file in quoted string starting at %s", start.Pos())}
+	}
+	flush()
+
+	return tokens, nil
+}
@@ -0,0 +1,120 @@
+package nginx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "directive",
+			input:    "listen 80;",
+			expected: []string{"listen", "80", ";"},
+		},
+		{
+			name:     "block",
+			input:    "server {\n  listen 80;\n}",
+			expected: []string{"server", "{", "listen", "80", ";", "}"},
+		},
+		{
+			name:     "quoted braces",
+			input:    `return 200 "{ok;}";`,
+			expected: []string{"return", "200", `"{ok;}"`, ";"},
+		},
+		{
+			name:     "escaped quote",
+			input:    `add_header X-Test 'it\'s';`,
+			expected: []string{"add_header", "X-Test", `"it's"`, ";"},
+		},
+		{
+			name:     "escape sequences",
+			input:    `set $a "tab\there\r\n\\ \d \'";`,
+			expected: []string{"set", "$a", `"tab\there\r\n\\ \\d '"`, ";"},
+		},
+		{
+			name:     "escape sequences outside quotes",
+			input:    `set $a a\tb\"c\.d;`,
+			expected: []string{"set", "$a", "a\tb\"c\\.d", ";"},
+		},
+		{
+			name:     "comment",
+			input:    "listen 80; # http\n",
+			expected: []string{"listen", "80", ";", "# http"},
+		},
+		{
+			name:     "hash inside word",
+			input:    "return 200 foo#bar;",
+			expected: []string{"return", "200", "foo#bar", ";"},
+		},
+		{
+			name:     "variable braces",
+			input:    "set $a ${host}x;",
+			expected: []string{"set", "$a", "${host}x", ";"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Lex(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Lex() error = %v", err)
+			}
+
+			var got []string
+			for _, tok := range tokens {
+				got = append(got, tok.String())
+			}
+
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Lex() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQuoteArgInvertsLex(t *testing.T) {
+	values := []string{
+		"plain",
+		"two words",
+		`\.php$`,
+		"tab\tand\nnewline\r",
+		`literal \t and \n`,
+		`ends in \`,
+		`quote " and '`,
+		`\"`,
+		"",
+	}
+	for _, value := range values {
+		source := "set $a " + QuoteArg(value) + ";"
+		tokens, err := Lex(strings.NewReader(source))
+		if err != nil {
+			t.Fatalf("Lex(%q) error = %v", source, err)
+		}
+		if len(tokens) != 4 || tokens[2].Value != value {
+			t.Errorf("Lex(%q) = %v, want the value %q back", source, tokens, value)
+		}
+	}
+}
+
+func TestLexPositions(t *testing.T) {
+	tokens, err := Lex(strings.NewReader("server {\n  listen 80;\n}"))
+	if err != nil {
+		t.Fatalf("Lex() error = %v", err)
+	}
+
+	listen := tokens[2]
+	if listen.Line != 2 || listen.Column != 3 {
+		t.Errorf("listen position = %s, want 2:3", listen.Pos())
+	}
+}
+
+func TestLexUnterminatedQuote(t *testing.T) {
+	if _, err := Lex(strings.NewReader(`return 200 "oops;`)); err == nil {
+		t.Error("Lex() expected error for unterminated quote")
+	}
+}
@@ -0,0 +1,54 @@
+package nginx
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// LineMap maps line numbers in formatted output back to the original file
+// by pairing up the tokens of two equivalent configurations
+type LineMap struct {
+	formatted []int
+	original  []int
+}
+
+// NewLineMap tokenizes both configurations and records the line of every
+// token. Comments are skipped when ignoreComments is set so that output
+// with comments removed still lines up.
+func NewLineMap(original, formatted io.Reader, ignoreComments bool) (*LineMap, error) {
+	want, err := Lex(original)
+	if err != nil {
+		return nil, fmt.Errorf("error tokenizing original: %w", err)
+	}
+	got, err := Lex(formatted)
+	if err != nil {
+		return nil, fmt.Errorf("error tokenizing formatted output: %w", err)
+	}
+
+	if ignoreComments {
+		want, got = withoutComments(want), withoutComments(got)
+	}
+
+	m := &LineMap{}
+	for i := 0; i < len(want) && i < len(got); i++ {
+		m.original = append(m.original, want[i].Line)
+		m.formatted = append(m.formatted, got[i].Line)
+	}
+
+	return m, nil
+}
+
+// Original returns the original line for a line of formatted output. Lines
+// without tokens map to the next token, or the last one at end of file.
+func (m *LineMap) Original(line int) int {
+	if len(m.original) == 0 {
+		return line
+	}
+
+	i := sort.SearchInts(m.formatted, line)
+	if i >= len(m.original) {
+		return m.original[len(m.original)-1]
+	}
+	return m.original[i]
+}
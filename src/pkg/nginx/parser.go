@@ -3,6 +3,7 @@ package nginx
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
@@ -29,7 +30,17 @@ func (f *Formatter) FormatFile(fileName string) ([]string, error) {
 	}
 	defer inputFile.Close()
 
-	scanner := bufio.NewScanner(inputFile)
+	formattedLines, err := f.Format(inputFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading input file: %w", err)
+	}
+
+	return formattedLines, nil
+}
+
+// Format formats nginx configuration read from r
+func (f *Formatter) Format(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
 
 	indentLevel := 0
 	var formattedLines []string
@@ -65,7 +76,7 @@ func (f *Formatter) FormatFile(fileName string) ([]string, error) {
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading input file: %w", err)
+		return nil, err
 	}
 
 	return formattedLines, nil
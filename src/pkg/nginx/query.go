@@ -0,0 +1,280 @@
+package nginx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Selector is a compiled query such as
+//
+//	http > server[server_name~=api] > location[args="/health"] > proxy_pass
+//
+// Steps are separated by '>' for a direct child or whitespace for any
+// descendant. The first step matches at any depth. Each step names a
+// directive, or '*' for any, followed by optional filters:
+//
+//	[args op value]  compares the directive's own arguments
+//	[name op value]  compares the arguments of a child directive called name
+//	[name]           requires a child directive called name
+//
+// Operators are = (equal), != (not equal), ^= (prefix), $= (suffix),
+// *= (contains) and ~= (regular expression). A filter matches when the
+// whole space-separated argument list or any single argument satisfies it.
+type Selector struct {
+	steps []step
+}
+
+type step struct {
+	name       string
+	descendant bool
+	filters    []filter
+}
+
+type filter struct {
+	attr  string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+// ParseSelector compiles a query string into a Selector
+func ParseSelector(query string) (*Selector, error) {
+	s := &Selector{}
+	q := strings.TrimSpace(query)
+	child := false
+
+	for len(q) > 0 {
+		switch {
+		case q[0] == '>':
+			if len(s.steps) == 0 || child {
+				return nil, fmt.Errorf("unexpected '>' in selector %q", query)
+			}
+			child = true
+			q = strings.TrimLeft(q[1:], " \t")
+			continue
+		case q[0] == ' ' || q[0] == '\t':
+			q = strings.TrimLeft(q, " \t")
+			continue
+		}
+
+		st, rest, err := parseStep(q)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", query, err)
+		}
+		st.descendant = len(s.steps) == 0 || !child
+		s.steps = append(s.steps, st)
+		child = false
+		q = rest
+	}
+
+	if len(s.steps) == 0 || child {
+		return nil, fmt.Errorf("incomplete selector %q", query)
+	}
+	return s, nil
+}
+
+func parseStep(q string) (step, string, error) {
+	i := 0
+	for i < len(q) && isNameChar(q[i]) {
+		i++
+	}
+	if i == 0 {
+		return step{}, "", fmt.Errorf("expected directive name at %q", q)
+	}
+	st := step{name: q[:i]}
+	q = q[i:]
+
+	for len(q) > 0 && q[0] == '[' {
+		end := closingBracket(q)
+		if end < 0 {
+			return step{}, "", fmt.Errorf("unterminated filter %q", q)
+		}
+		f, err := parseFilter(q[1:end])
+		if err != nil {
+			return step{}, "", err
+		}
+		st.filters = append(st.filters, f)
+		q = q[end+1:]
+	}
+
+	return st, q, nil
+}
+
+func parseFilter(body string) (filter, error) {
+	body = strings.TrimSpace(body)
+	i := 0
+	for i < len(body) && isNameChar(body[i]) && body[i] != '*' {
+		i++
+	}
+	if i == 0 {
+		return filter{}, fmt.Errorf("expected attribute name in [%s]", body)
+	}
+	f := filter{attr: body[:i]}
+	rest := strings.TrimSpace(body[i:])
+	if rest == "" {
+		return f, nil
+	}
+
+	for _, op := range []string{"!=", "^=", "$=", "*=", "~=", "="} {
+		if strings.HasPrefix(rest, op) {
+			f.op = op
+			rest = strings.TrimSpace(rest[len(op):])
+			break
+		}
+	}
+	if f.op == "" {
+		return filter{}, fmt.Errorf("unknown operator in [%s]", body)
+	}
+
+	if len(rest) >= 2 && (rest[0] == '"' || rest[0] == '\'') && rest[len(rest)-1] == rest[0] {
+		rest = rest[1 : len(rest)-1]
+	}
+	f.value = rest
+
+	if f.op == "~=" {
+		re, err := regexp.Compile(f.value)
+		if err != nil {
+			return filter{}, fmt.Errorf("invalid regular expression in [%s]: %w", body, err)
+		}
+		f.re = re
+	}
+	return f, nil
+}
+
+// closingBracket finds the ']' ending the filter at the start of q,
+// skipping over quoted values
+func closingBracket(q string) int {
+	var quote byte
+	for i := 1; i < len(q); i++ {
+		switch {
+		case quote != 0:
+			if q[i] == quote {
+				quote = 0
+			}
+		case q[i] == '"' || q[i] == '\'':
+			quote = q[i]
+		case q[i] == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c == '-' || c == '*' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// Select returns every directive matching the selector in document order.
+// expand, when not nil, is applied to each block before it is searched so
+// callers can splice in included files.
+func (s *Selector) Select(directives []*Directive, expand func([]*Directive) []*Directive) []*Directive {
+	if expand == nil {
+		expand = func(d []*Directive) []*Directive { return d }
+	}
+
+	var matches []*Directive
+	seen := make(map[*Directive]bool)
+	s.match(expand(directives), 0, expand, func(d *Directive) {
+		if !seen[d] {
+			seen[d] = true
+			matches = append(matches, d)
+		}
+	})
+	return matches
+}
+
+func (s *Selector) match(directives []*Directive, i int, expand func([]*Directive) []*Directive, emit func(*Directive)) {
+	st := s.steps[i]
+	for _, d := range directives {
+		if d.IsComment() {
+			continue
+		}
+
+		if st.matches(d, expand) {
+			if i == len(s.steps)-1 {
+				emit(d)
+			} else if d.IsBlock() {
+				s.match(expand(d.Block), i+1, expand, emit)
+			}
+		}
+
+		if st.descendant && d.IsBlock() {
+			s.match(expand(d.Block), i, expand, emit)
+		}
+	}
+}
+
+func (st step) matches(d *Directive, expand func([]*Directive) []*Directive) bool {
+	if st.name != "*" && st.name != d.Name {
+		return false
+	}
+
+	for _, f := range st.filters {
+		if f.attr == "args" {
+			if (f.op == "" && len(d.Args) == 0) || (f.op != "" && !f.matches(d.Args)) {
+				return false
+			}
+			continue
+		}
+
+		found := false
+		if d.IsBlock() {
+			for _, child := range expand(d.Block) {
+				if child.Name == f.attr && (f.op == "" || f.matches(child.Args)) {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f filter) matches(args []string) bool {
+	if f.op == "!=" {
+		return !filter{attr: f.attr, op: "=", value: f.value}.matches(args)
+	}
+
+	candidates := append([]string{strings.Join(args, " ")}, args...)
+	for _, arg := range candidates {
+		var ok bool
+		switch f.op {
+		case "=":
+			ok = arg == f.value
+		case "^=":
+			ok = strings.HasPrefix(arg, f.value)
+		case "$=":
+			ok = strings.HasSuffix(arg, f.value)
+		case "*=":
+			ok = strings.Contains(arg, f.value)
+		case "~=":
+			ok = f.re.MatchString(arg)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Parent returns a selector for the block a directive matched by the last
+// step lives in. It returns nil unless the last step is a direct child of
+// the previous one, since only then is the parent unambiguous.
+func (s *Selector) Parent() *Selector {
+	last := s.steps[len(s.steps)-1]
+	if len(s.steps) < 2 || last.descendant {
+		return nil
+	}
+	return &Selector{steps: s.steps[:len(s.steps)-1]}
+}
+
+// Name returns the directive name matched by the last step, or "*"
+func (s *Selector) Name() string {
+	return s.steps[len(s.steps)-1].name
+}
@@ -0,0 +1,138 @@
+package nginx
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelectorSelect(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"nginx.conf": `http {
+    upstream backend { server 127.0.0.1:8080; }
+    include sites/*.conf;
+}`,
+		"sites/api.conf": `server {
+    server_name api.example.com;
+    location = /health {
+        proxy_pass http://health;
+    }
+    location /v1/ {
+        proxy_pass http://backend;
+    }
+}`,
+		"sites/www.conf": `server {
+    server_name www.example.com example.com;
+    location / {
+        root /var/www;
+    }
+    location /health {
+        return 200;
+    }
+}`,
+	})
+	entry := filepath.Join(root, "nginx.conf")
+	api := filepath.Join(root, "sites/api.conf")
+	www := filepath.Join(root, "sites/www.conf")
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{
+			query:    `http > server[server_name~=api] > location[args="/health"] > proxy_pass`,
+			expected: []string{api + ":4"},
+		},
+		{
+			query:    `proxy_pass`,
+			expected: []string{api + ":4", api + ":7"},
+		},
+		{
+			query:    `server[server_name=example.com] location`,
+			expected: []string{www + ":3", www + ":6"},
+		},
+		{
+			query:    `location[proxy_pass$=backend]`,
+			expected: []string{api + ":6"},
+		},
+		{
+			query:    `location[return]`,
+			expected: []string{www + ":6"},
+		},
+		{
+			query:    `upstream > server[args^=127.]`,
+			expected: []string{entry + ":2"},
+		},
+		{
+			query:    `http > location`,
+			expected: nil,
+		},
+		{
+			query:    `server[server_name!=api.example.com] > *[args*=health]`,
+			expected: []string{www + ":6"},
+		},
+	}
+
+	g := LoadGraph(root, entry)
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			s, err := ParseSelector(tt.query)
+			if err != nil {
+				t.Fatalf("ParseSelector() error = %v", err)
+			}
+
+			var got []string
+			for _, d := range g.Query(s, entry) {
+				got = append(got, fmt.Sprintf("%s:%d", d.File, d.Line))
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Query() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"> server",
+		"server >",
+		"server > > location",
+		"server[server_name",
+		"server[server_name ?= x]",
+		"server[server_name~=(]",
+	} {
+		if _, err := ParseSelector(query); err == nil {
+			t.Errorf("ParseSelector(%q) expected error", query)
+		}
+	}
+}
+
+func TestSelectorParent(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantParent bool
+		wantName   string
+	}{
+		{query: "server[server_name=example.com] > client_max_body_size", wantParent: true, wantName: "client_max_body_size"},
+		{query: "server client_max_body_size", wantName: "client_max_body_size"},
+		{query: "gzip", wantName: "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			s, err := ParseSelector(tt.query)
+			if err != nil {
+				t.Fatalf("ParseSelector() error = %v", err)
+			}
+			if got := s.Parent() != nil; got != tt.wantParent {
+				t.Errorf("Parent() != nil is %v, want %v", got, tt.wantParent)
+			}
+			if s.Name() != tt.wantName {
+				t.Errorf("Name() = %s, want %s", s.Name(), tt.wantName)
+			}
+		})
+	}
+}
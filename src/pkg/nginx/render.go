@@ -0,0 +1,107 @@
+package nginx
+
+import (
+	"strings"
+)
+
+// Render prints a directive tree and formats the result, so rendered
+// configuration is laid out exactly like the output of Format
+func (f *Formatter) Render(directives []*Directive) ([]string, error) {
+	var b strings.Builder
+	render(&b, directives)
+	return f.Format(strings.NewReader(b.String()))
+}
+
+func render(b *strings.Builder, directives []*Directive) {
+	for i := 0; i < len(directives); i++ {
+		d := directives[i]
+		if d.IsComment() {
+			b.WriteString("#" + d.Comment + "\n")
+			continue
+		}
+
+		b.WriteString(d.Name)
+		writeArgs(b, d)
+
+		if !d.IsBlock() {
+			b.WriteString(";")
+			// keep a trailing comment on the line it was written on
+			if i+1 < len(directives) {
+				next := directives[i+1]
+				if next.IsComment() && next.Line == d.Line && next.File == d.File && d.Line > 0 {
+					b.WriteString(" #" + next.Comment)
+					i++
+				}
+			}
+			b.WriteString("\n")
+			continue
+		}
+
+		b.WriteString(" {\n")
+		render(b, d.Block)
+		b.WriteString("}\n")
+	}
+}
+
+// writeArgs writes the arguments of d, each preceded by a space, as they
+// were written in the source when that is known. The condition of an if
+// directive is put back in parentheses.
+func writeArgs(b *strings.Builder, d *Directive) {
+	quoted := make([]string, len(d.Args))
+	for i, arg := range d.Args {
+		if len(d.Raw) == len(d.Args) && d.Raw[i] != "" {
+			quoted[i] = d.Raw[i]
+		} else {
+			quoted[i] = QuoteArg(arg)
+		}
+	}
+	if d.Name == "if" && d.IsBlock() {
+		b.WriteString(" (" + strings.Join(quoted, " ") + ")")
+		return
+	}
+	for _, arg := range quoted {
+		b.WriteString(" " + arg)
+	}
+}
+
+// QuoteArg returns arg as it must be written in a configuration file,
+// quoting it when it contains characters that would otherwise end the word
+// or be mistaken for structure by the formatter. It is the inverse of Lex:
+// tabs, carriage returns and newlines are written as \t, \r and \n, and a
+// backslash is only escaped where Lex would otherwise unescape it.
+func QuoteArg(arg string) string {
+	plain := arg != "" && !strings.ContainsAny(arg, " \t\r\n;{}#'\"")
+	for i := 0; plain && i < len(arg); i++ {
+		plain = !escapes(arg, i)
+	}
+	if plain {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i, r := range arg {
+		switch {
+		case r == '\t':
+			b.WriteString(`\t`)
+			continue
+		case r == '\r':
+			b.WriteString(`\r`)
+			continue
+		case r == '\n':
+			b.WriteString(`\n`)
+			continue
+		case r == '"' || escapes(arg, i):
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// escapes reports whether arg[i] is a backslash Lex would read as the
+// start of an escape sequence, or one that would escape a closing quote
+func escapes(arg string, i int) bool {
+	return arg[i] == '\\' && (i+1 == len(arg) || strings.IndexByte(`"'\trn`, arg[i+1]) >= 0)
+}
@@ -0,0 +1,92 @@
+package nginx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// EquivalenceError reports the first token where the formatted output
+// differs from the original configuration. A nil Original or Formatted
+// means that side ended early.
+type EquivalenceError struct {
+	Original  *Token
+	Formatted *Token
+}
+
+func (e *EquivalenceError) Error() string {
+	describe := func(t *Token) string {
+		if t == nil {
+			return "end of file"
+		}
+		return fmt.Sprintf("%s %s at %s", t.Kind, t, t.Pos())
+	}
+	return fmt.Sprintf("formatted output is not equivalent to the original: original has %s, formatted has %s",
+		describe(e.Original), describe(e.Formatted))
+}
+
+// Equivalent tokenizes both configurations and returns an
+// *EquivalenceError if nginx would read them differently. Comments are
+// compared unless ignoreComments is set.
+func Equivalent(original, formatted io.Reader, ignoreComments bool) error {
+	want, err := Lex(original)
+	if err != nil {
+		return fmt.Errorf("error tokenizing original: %w", err)
+	}
+	got, err := Lex(formatted)
+	if err != nil {
+		return fmt.Errorf("error tokenizing formatted output: %w", err)
+	}
+
+	if ignoreComments {
+		want, got = withoutComments(want), withoutComments(got)
+	}
+
+	for i := 0; i < len(want) || i < len(got); i++ {
+		var a, b *Token
+		if i < len(want) {
+			a = &want[i]
+		}
+		if i < len(got) {
+			b = &got[i]
+		}
+		if a == nil || b == nil || !sameToken(*a, *b) {
+			return &EquivalenceError{Original: a, Formatted: b}
+		}
+	}
+
+	return nil
+}
+
+// CheckFile verifies that formatted is equivalent to the contents of
+// fileName, ignoring comments when the formatter removes them
+func (f *Formatter) CheckFile(fileName string, formatted []string) error {
+	original, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("error opening input file: %w", err)
+	}
+	defer original.Close()
+
+	return Equivalent(original, strings.NewReader(strings.Join(formatted, "\n")), f.RemoveComments)
+}
+
+func sameToken(a, b Token) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	if a.Kind == TokenComment {
+		return strings.TrimSpace(a.Value) == strings.TrimSpace(b.Value)
+	}
+	return a.Value == b.Value && a.Quoted == b.Quoted
+}
+
+func withoutComments(tokens []Token) []Token {
+	result := tokens[:0:0]
+	for _, t := range tokens {
+		if t.Kind != TokenComment {
+			result = append(result, t)
+		}
+	}
+	return result
+}
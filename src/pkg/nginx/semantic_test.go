@@ -0,0 +1,78 @@
+package nginx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEquivalent(t *testing.T) {
+	tests := []struct {
+		name           string
+		original       string
+		formatted      string
+		ignoreComments bool
+		wantErr        bool
+	}{
+		{
+			name:      "whitespace only",
+			original:  "server{listen 80;}",
+			formatted: "server {\n  listen 80;\n}\n",
+		},
+		{
+			name:           "comments removed",
+			original:       "# main\nserver { listen 80; # http\n}",
+			formatted:      "server {\n  listen 80;\n}\n",
+			ignoreComments: true,
+		},
+		{
+			name:      "comments dropped",
+			original:  "# main\nserver { listen 80; }",
+			formatted: "server {\n  listen 80;\n}\n",
+			wantErr:   true,
+		},
+		{
+			name:      "argument changed",
+			original:  "return 200 foo#bar;",
+			formatted: "return 200 foo",
+			wantErr:   true,
+		},
+		{
+			name:      "quoting changed",
+			original:  `return 200 "ok";`,
+			formatted: `return 200 ok;`,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Equivalent(strings.NewReader(tt.original), strings.NewReader(tt.formatted), tt.ignoreComments)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("Equivalent() error = %v", err)
+				}
+				return
+			}
+
+			var eqErr *EquivalenceError
+			if !errors.As(err, &eqErr) {
+				t.Errorf("Equivalent() error = %v, want *EquivalenceError", err)
+			}
+		})
+	}
+}
+
+func TestFormatIsEquivalent(t *testing.T) {
+	input := `http{ server{ location / { return 200 "{}"; }} server_name example.com;}`
+
+	f := New(2, false, false)
+	formatted, err := f.Format(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	if err := Equivalent(strings.NewReader(input), strings.NewReader(strings.Join(formatted, "\n")), false); err != nil {
+		t.Errorf("Equivalent() error = %v", err)
+	}
+}
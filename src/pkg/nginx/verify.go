@@ -0,0 +1,45 @@
+package nginx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IdempotencyError reports the first line where a second formatting pass
+// disagrees with the first
+type IdempotencyError struct {
+	Line   int
+	First  string
+	Second string
+}
+
+func (e *IdempotencyError) Error() string {
+	return fmt.Sprintf("formatting is not idempotent: line %d is %s after the first pass but %s after the second",
+		e.Line, e.First, e.Second)
+}
+
+// Verify formats already formatted output a second time and returns an
+// *IdempotencyError if the second pass differs from the first
+func (f *Formatter) Verify(formatted []string) error {
+	second, err := f.Format(strings.NewReader(strings.Join(formatted, "\n") + "\n"))
+	if err != nil {
+		return fmt.Errorf("error reformatting output: %w", err)
+	}
+
+	for i := 0; i < len(formatted) || i < len(second); i++ {
+		first, again := lineAt(formatted, i), lineAt(second, i)
+		if first != again {
+			return &IdempotencyError{Line: i + 1, First: first, Second: again}
+		}
+	}
+
+	return nil
+}
+
+// lineAt quotes line i for error output, or returns <EOF> past the end
+func lineAt(lines []string, i int) string {
+	if i >= len(lines) {
+		return "<EOF>"
+	}
+	return fmt.Sprintf("%q", lines[i])
+}
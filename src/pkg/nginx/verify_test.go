@@ -0,0 +1,57 @@
+package nginx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantLine int
+	}{
+		{
+			name: "idempotent",
+			input: `http {
+server {
+listen 80;
+}
+}`,
+		},
+		{
+			name: "comment after opening brace",
+			input: `server { # main
+listen 80;
+}`,
+			wantLine: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := New(2, false, false)
+			formatted, err := f.Format(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+
+			err = f.Verify(formatted)
+			if tt.wantLine == 0 {
+				if err != nil {
+					t.Errorf("Verify() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var idemErr *IdempotencyError
+			if !errors.As(err, &idemErr) {
+				t.Fatalf("Verify() error = %v, want *IdempotencyError", err)
+			}
+			if idemErr.Line != tt.wantLine {
+				t.Errorf("Verify() line = %d, want %d", idemErr.Line, tt.wantLine)
+			}
+		})
+	}
+}